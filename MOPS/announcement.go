@@ -0,0 +1,328 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/transform"
+)
+
+var enc = traditionalchinese.Big5
+
+// 重大訊息, POST step=1 lists a day, step=2 with the fields of the 詳細資料 button shows one announcement
+const urlAnnouncement = "http://mops.twse.com.tw/mops/web/ajax_t05st02"
+const kMinDate = 20000000
+
+const (
+	DB_USER     = "stock"
+	DB_PASSWORD = "test"
+	DB_NAME     = "stock"
+	DB_HOST     = "data.example.com"
+)
+
+const insertSql = `INSERT INTO announcements (security_code, spoke_time, seq_no, subject, body, category, fact_date) VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (security_code, spoke_time, seq_no) DO UPDATE SET subject = EXCLUDED.subject, body = EXCLUDED.body, category = EXCLUDED.category, fact_date = EXCLUDED.fact_date`
+
+// document.fm.co_id.value='2330';
+var buttonValue = regexp.MustCompile(`\.(\w+)\.value\s*=\s*['"]([^'"]*)['"]`)
+
+type Announcement struct {
+	SecurityCode string
+	SpokeTime    string
+	SeqNo        string
+	Subject      string
+	Body         string
+	Category     string // 符合條款
+	FactDate     string // 事實發生日
+	detail       url.Values
+}
+
+var flagFromDate = flag.Int("f", 0, "from date YYYYMMDD (default: the latest announcement date in DB)")
+var flagToDate = flag.Int("t", 0, "to date YYYYMMDD (default: today)")
+var flagLastTradeDay = flag.Bool("l", false, "today only")
+
+func main() {
+	flag.Parse()
+	dbinfo := fmt.Sprintf("user=%s password=%s dbname=%s host=%s sslmode=disable", DB_USER, DB_PASSWORD, DB_NAME, DB_HOST)
+	db, err := sql.Open("postgres", dbinfo)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	today := time.Now()
+	fromDate := *flagFromDate
+	toDate := *flagToDate
+	if fromDate == 0 || fromDate < kMinDate {
+		fromDate, err = fetchLastAnnouncementDate(db)
+		if err != nil {
+			fromDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+		}
+	}
+	if toDate == 0 || toDate < kMinDate {
+		toDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+	}
+
+	log.Println(fromDate, toDate)
+
+	local, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		panic(err)
+	}
+
+	var quoteDate, beginDate time.Time
+	if *flagLastTradeDay {
+		quoteDate = today
+	} else {
+		quoteDate = time.Date(toDate/10000, time.Month(toDate%10000/100), toDate%100, 1, 0, 0, 0, local)
+	}
+	beginDate = time.Date(fromDate/10000, time.Month(fromDate%10000/100), fromDate%100, 0, 0, 0, 0, local)
+	log.Println("beginDate = ", beginDate)
+	for quoteDate.After(beginDate) {
+		log.Println(quoteDate)
+		time.Sleep(1 * time.Second)
+		announcements, ok := fetchAnnouncements(quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day())
+		if ok {
+			for i := range announcements {
+				time.Sleep(500 * time.Millisecond)
+				if fetchAnnouncementDetail(&announcements[i]) {
+					writeAnnouncement(db, &announcements[i])
+				}
+			}
+		}
+		if *flagLastTradeDay {
+			break
+		}
+		quoteDate = quoteDate.AddDate(0, 0, -1)
+	}
+}
+
+func fetchLastAnnouncementDate(db *sql.DB) (int, error) {
+	var row1 string
+	// the latest day may be incomplete, fetch it again
+	sqlString := "SELECT to_char(MAX(spoke_time), 'YYYYMMDD') FROM announcements"
+	err := db.QueryRow(sqlString).Scan(&row1)
+	if err != nil {
+		return 0, err
+	}
+	log.Println("the latest announcement day = ", row1)
+
+	return strconv.Atoi(row1)
+}
+
+func postForm(form url.Values) (*html.Node, bool) {
+	log.Println(urlAnnouncement, form.Encode())
+	resp, err := http.PostForm(urlAnnouncement, form)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Println("status no OK")
+		return nil, false
+	}
+
+	r := transform.NewReader(resp.Body, enc.NewDecoder())
+	doc, err := html.Parse(r)
+	if err != nil {
+		log.Println("htmlparser: ", err)
+		return nil, false
+	}
+	return doc, true
+}
+
+func fetchAnnouncements(year, month, day int) ([]Announcement, bool) {
+	form := url.Values{}
+	form.Set("encodeURIComponent", "1")
+	form.Set("step", "1")
+	form.Set("firstin", "1")
+	form.Set("off", "1")
+	form.Set("TYPEK", "all")
+	form.Set("year", fmt.Sprint(year-1911))
+	form.Set("month", fmt.Sprintf("%02d", month))
+	form.Set("day", fmt.Sprintf("%02d", day))
+
+	doc, ok := postForm(form)
+	if !ok {
+		return nil, false
+	}
+
+	var announcements []Announcement
+	listRows(doc, &announcements)
+	if len(announcements) == 0 {
+		return nil, false
+	}
+	return announcements, true
+}
+
+// listRows walks the day list like noTotal in revenue.go, every data row
+// has td cells 公司代號,公司名稱,發言日期,發言時間,主旨 and a 詳細資料 button.
+func listRows(n *html.Node, announcements *[]Announcement) {
+	if n.Type == html.ElementNode && n.Data == "tr" {
+		var cells []string
+		detail := url.Values{}
+		nested := false
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || c.Data != "td" {
+				continue
+			}
+			if hasTable(c) {
+				// layout row, the list is further down
+				nested = true
+				break
+			}
+			cells = append(cells, strings.TrimSpace(nodeText(c)))
+			buttonValues(c, detail)
+		}
+		if !nested && len(cells) >= 5 && len(detail) > 0 {
+			var a Announcement
+			a.SecurityCode = cells[0]
+			a.SpokeTime = rocDate(cells[2]) + " " + cells[3]
+			a.SeqNo = detail.Get("seq_no")
+			if len(a.SeqNo) == 0 {
+				a.SeqNo = detail.Get("SEQ_NO")
+			}
+			a.Subject = cells[4]
+			a.detail = detail
+			*announcements = append(*announcements, a)
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		listRows(c, announcements)
+	}
+}
+
+func hasTable(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "table" || hasTable(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// buttonValues collects the form values set by the onclick of a button.
+func buttonValues(n *html.Node, values url.Values) {
+	if n.Type == html.ElementNode && n.Data == "input" {
+		for _, attr := range n.Attr {
+			if attr.Key != "onclick" {
+				continue
+			}
+			for _, m := range buttonValue.FindAllStringSubmatch(attr.Val, -1) {
+				values.Set(m[1], m[2])
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		buttonValues(c, values)
+	}
+}
+
+func fetchAnnouncementDetail(a *Announcement) bool {
+	form := url.Values{}
+	for key, values := range a.detail {
+		form[key] = values
+	}
+	form.Set("encodeURIComponent", "1")
+	form.Set("step", "2")
+	form.Set("firstin", "1")
+	form.Set("off", "1")
+
+	doc, ok := postForm(form)
+	if !ok {
+		return false
+	}
+
+	// label cells are followed by their value cell
+	var rows [][]string
+	tableRows(doc, &rows)
+	for _, row := range rows {
+		for i := 0; i+1 < len(row); i++ {
+			switch {
+			case strings.Contains(row[i], "主旨"):
+				a.Subject = row[i+1]
+			case strings.Contains(row[i], "說明"):
+				a.Body = row[i+1]
+			case strings.Contains(row[i], "符合條款"):
+				a.Category = row[i+1]
+			case strings.Contains(row[i], "事實發生日"):
+				a.FactDate = rocDate(row[i+1])
+			}
+		}
+	}
+	return len(a.Subject) > 0
+}
+
+func tableRows(n *html.Node, rows *[][]string) {
+	if n.Type == html.ElementNode && n.Data == "tr" {
+		var row []string
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.Data == "td" || c.Data == "th") {
+				row = append(row, strings.TrimSpace(nodeText(c)))
+			}
+		}
+		*rows = append(*rows, row)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		tableRows(c, rows)
+	}
+}
+
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	if n.Type == html.ElementNode && n.Data == "br" {
+		return "\n"
+	}
+	text := ""
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		text += nodeText(c)
+	}
+	return text
+}
+
+// rocDate converts 107/06/29 or 1070629 to 2018/06/29
+func rocDate(s string) string {
+	s = strings.Replace(strings.TrimSpace(s), "/", "", -1)
+	date, err := strconv.Atoi(s)
+	if err != nil || date == 0 {
+		return ""
+	}
+	date += 19110000
+	return fmt.Sprintf("%04d/%02d/%02d", date/10000, date%10000/100, date%100)
+}
+
+func nullString(s string) interface{} {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
+
+func writeAnnouncement(db *sql.DB, a *Announcement) bool {
+	_, err := db.Exec(insertSql, a.SecurityCode, a.SpokeTime, a.SeqNo, a.Subject, a.Body, nullString(a.Category), nullString(a.FactDate))
+	if err != nil {
+		log.Println("writeAnnouncement", err)
+		return false
+	}
+	return true
+}
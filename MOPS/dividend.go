@@ -0,0 +1,251 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/transform"
+)
+
+var enc = traditionalchinese.Big5
+
+// 決議分派股利彙總表, POST TYPEK=sii/otc&YEAR=107
+const urlDividendPolicy = "http://mops.twse.com.tw/mops/web/ajax_t05st09_2"
+
+const (
+	DB_USER     = "stock"
+	DB_PASSWORD = "test"
+	DB_NAME     = "stock"
+	DB_HOST     = "data.example.com"
+)
+
+var markets = [...]string{"sii", "otc"}
+
+type DividendPolicy struct {
+	SecurityCode  string
+	Period        string // 股利所屬期間
+	BoardDate     string // 董事會決議(擬議)股利分派日
+	AGMDate       string // 股東會日期
+	CashEarnings  string // 盈餘分配之現金股利(元/股)
+	CashReserve   string // 法定盈餘公積、資本公積發放之現金(元/股)
+	StockEarnings string // 盈餘轉增資配股(元/股)
+	StockReserve  string // 法定盈餘公積、資本公積轉增資配股(元/股)
+	ExDate        string // 除息交易日
+	PaymentDate   string // 現金股利發放日
+}
+
+var flagYear = flag.Int("y", 0, "dividend year YYYY (default: last year)")
+
+func main() {
+	flag.Parse()
+	dbinfo := fmt.Sprintf("user=%s password=%s dbname=%s host=%s sslmode=disable", DB_USER, DB_PASSWORD, DB_NAME, DB_HOST)
+	db, err := sql.Open("postgres", dbinfo)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	year := *flagYear
+	if year == 0 {
+		year = time.Now().Year() - 1
+	}
+
+	for _, market := range markets {
+		time.Sleep(1 * time.Second)
+		policies, ok := fetchDividendPolicies(market, year)
+		if !ok {
+			continue
+		}
+		//printDividendPolicies(policies)
+		writeDividendPolicies(db, year, policies)
+	}
+}
+
+func fetchDividendPolicies(market string, year int) ([]DividendPolicy, bool) {
+	form := url.Values{}
+	form.Set("encodeURIComponent", "1")
+	form.Set("step", "1")
+	form.Set("firstin", "1")
+	form.Set("off", "1")
+	form.Set("TYPEK", market)
+	form.Set("YEAR", fmt.Sprint(year-1911))
+	form.Set("qryType", "1")
+
+	log.Println(urlDividendPolicy, form.Encode())
+	resp, err := http.PostForm(urlDividendPolicy, form)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Println("status no OK")
+		return nil, false
+	}
+
+	r := transform.NewReader(resp.Body, enc.NewDecoder())
+	doc, err := html.Parse(r)
+	if err != nil {
+		log.Println("htmlparser: ", err)
+		return nil, false
+	}
+
+	var rows [][]string
+	tableRows(doc, &rows)
+
+	var header []string
+	var keys []string
+	policies := make(map[string]DividendPolicy)
+	for _, row := range rows {
+		if len(row) > 0 && strings.Contains(row[0], "公司代號") {
+			header = row
+			continue
+		}
+		if header == nil || len(row) != len(header) {
+			continue
+		}
+		// "2330 台積電", blank on subtotal rows
+		fields := strings.Fields(row[0])
+		if len(fields) == 0 {
+			continue
+		}
+		policy := DividendPolicy{
+			SecurityCode:  fields[0],
+			Period:        column(row, header, "股利所屬期間", "股利所屬年"),
+			BoardDate:     rocDate(column(row, header, "董事會決議")),
+			AGMDate:       rocDate(column(row, header, "股東會日期")),
+			CashEarnings:  number(column(row, header, "盈餘分配之現金股利")),
+			CashReserve:   number(column(row, header, "資本公積發放之現金")),
+			StockEarnings: number(column(row, header, "盈餘轉增資配股")),
+			StockReserve:  number(column(row, header, "資本公積轉增資配股")),
+			ExDate:        rocDate(column(row, header, "除息交易日")),
+			PaymentDate:   rocDate(column(row, header, "現金股利發放日")),
+		}
+		if len(policy.SecurityCode) == 0 || len(policy.Period) == 0 {
+			continue
+		}
+		// keep the latest resolution of the same period
+		key := policy.SecurityCode + policy.Period
+		if _, ok := policies[key]; !ok {
+			keys = append(keys, key)
+		}
+		policies[key] = policy
+	}
+
+	if len(keys) == 0 {
+		return nil, false
+	}
+	var out []DividendPolicy
+	for _, key := range keys {
+		out = append(out, policies[key])
+	}
+	return out, true
+}
+
+// tableRows collects the text of th/td cells of every table row.
+func tableRows(n *html.Node, rows *[][]string) {
+	if n.Type == html.ElementNode && n.Data == "tr" {
+		var row []string
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.Data == "td" || c.Data == "th") {
+				row = append(row, strings.TrimSpace(nodeText(c)))
+			}
+		}
+		*rows = append(*rows, row)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		tableRows(c, rows)
+	}
+}
+
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	text := ""
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		text += nodeText(c)
+	}
+	return text
+}
+
+func column(row, header []string, names ...string) string {
+	for _, name := range names {
+		for i, field := range header {
+			if strings.Contains(field, name) {
+				return strings.TrimSpace(row[i])
+			}
+		}
+	}
+	return ""
+}
+
+// rocDate converts 107/03/15 to 2018/03/15
+func rocDate(s string) string {
+	var year, month, day int
+	if n, _ := fmt.Sscanf(strings.TrimSpace(s), "%d/%d/%d", &year, &month, &day); n != 3 {
+		return ""
+	}
+	return fmt.Sprintf("%04d/%02d/%02d", year+1911, month, day)
+}
+
+func number(s string) string {
+	s = strings.Replace(strings.TrimSpace(s), ",", "", -1)
+	if strings.Contains(s, "--") || len(s) == 0 {
+		return ""
+	}
+	return s
+}
+
+func sqlValue(s string) string {
+	if len(s) == 0 {
+		return "null"
+	}
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+func printDividendPolicies(policies []DividendPolicy) {
+	for _, p := range policies {
+		fmt.Println(p.SecurityCode, p.Period, p.BoardDate, p.AGMDate, p.CashEarnings, p.CashReserve, p.StockEarnings, p.StockReserve, p.ExDate, p.PaymentDate)
+	}
+}
+
+func writeDividendPolicies(db *sql.DB, year int, policies []DividendPolicy) bool {
+	sqlString := "INSERT INTO dividend_policies (security_code, dividend_year, period, board_date, agm_date, cash_earnings, cash_reserve, stock_earnings, stock_reserve, ex_date, payment_date) VALUES\n"
+	for _, p := range policies {
+		sqlString += fmt.Sprintf("('%s', '%d', %s, %s, %s, %s, %s, %s, %s, %s, %s),\n", p.SecurityCode, year,
+			sqlValue(p.Period), sqlValue(p.BoardDate), sqlValue(p.AGMDate),
+			sqlValue(p.CashEarnings), sqlValue(p.CashReserve), sqlValue(p.StockEarnings), sqlValue(p.StockReserve),
+			sqlValue(p.ExDate), sqlValue(p.PaymentDate))
+	}
+	sqlString = strings.TrimRight(sqlString, ",\n")
+	sqlString += `
+ON CONFLICT (security_code, dividend_year, period) DO UPDATE SET
+	board_date = EXCLUDED.board_date, agm_date = EXCLUDED.agm_date,
+	cash_earnings = EXCLUDED.cash_earnings, cash_reserve = EXCLUDED.cash_reserve,
+	stock_earnings = EXCLUDED.stock_earnings, stock_reserve = EXCLUDED.stock_reserve,
+	ex_date = COALESCE(EXCLUDED.ex_date, dividend_policies.ex_date),
+	payment_date = COALESCE(EXCLUDED.payment_date, dividend_policies.payment_date);`
+	//fmt.Println(sqlString)
+	_, err := db.Exec(sqlString)
+	if err != nil {
+		log.Println("writeDividendPolicies", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// 上市公司基本資料, one row per company, updated daily
+const urlTSECompanyInfo = "http://mopsfin.twse.com.tw/opendata/t187ap03_L.csv"
+
+const (
+	DB_USER     = "stock"
+	DB_PASSWORD = "test"
+	DB_NAME     = "stock"
+	DB_HOST     = "data.example.com"
+)
+
+type SharesOutstanding struct {
+	SecurityCode string
+	Shares       string // 已發行普通股數或TWSE認可股數
+}
+
+var flagPrint = flag.Bool("p", false, "print only, do not write DB")
+
+func main() {
+	flag.Parse()
+
+	date, shares, ok := fetchSharesOutstanding(urlTSECompanyInfo)
+	if !ok {
+		return
+	}
+	if *flagPrint {
+		printSharesOutstanding(date, shares)
+		return
+	}
+
+	dbinfo := fmt.Sprintf("user=%s password=%s dbname=%s host=%s sslmode=disable", DB_USER, DB_PASSWORD, DB_NAME, DB_HOST)
+	db, err := sql.Open("postgres", dbinfo)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	writeSharesOutstanding(db, date, shares)
+}
+
+func columnIndex(header []string, names ...string) int {
+	for _, name := range names {
+		for i, field := range header {
+			if strings.Contains(field, name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// rocDate converts 1070629 or 107/06/29 to 2018/06/29
+func rocDate(s string) string {
+	s = strings.Replace(strings.TrimSpace(s), "/", "", -1)
+	date, err := strconv.Atoi(s)
+	if err != nil || date == 0 {
+		return ""
+	}
+	if date < 20000000 {
+		date += 19110000
+	}
+	return fmt.Sprintf("%04d/%02d/%02d", date/10000, date%10000/100, date%100)
+}
+
+// fetchSharesOutstanding returns the 出表日期 and the shares of every company.
+// The file is a snapshot, so history builds up by running this every day.
+func fetchSharesOutstanding(url string) (string, []SharesOutstanding, bool) {
+	log.Println(url)
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Println(err)
+		return "", nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, false
+	}
+
+	csvr := csv.NewReader(resp.Body)
+	csvr.FieldsPerRecord = -1
+	records, err := csvr.ReadAll()
+	if err != nil || len(records) < 2 {
+		log.Println(err)
+		return "", nil, false
+	}
+
+	header := records[0]
+	dateIndex := columnIndex(header, "出表日期")
+	codeIndex := columnIndex(header, "公司代號")
+	sharesIndex := columnIndex(header, "已發行普通股數", "發行股數")
+	if codeIndex < 0 || sharesIndex < 0 {
+		log.Println("unknown company information header", header)
+		return "", nil, false
+	}
+
+	date := time.Now().Format("2006/01/02")
+	if dateIndex >= 0 && len(records[1]) > dateIndex {
+		if d := rocDate(records[1][dateIndex]); len(d) > 0 {
+			date = d
+		}
+	}
+
+	var shares []SharesOutstanding
+	for _, record := range records[1:] {
+		if len(record) < len(header) {
+			continue
+		}
+		n := strings.Replace(strings.TrimSpace(record[sharesIndex]), ",", "", -1)
+		if _, err := strconv.ParseInt(n, 10, 64); err != nil {
+			continue
+		}
+		shares = append(shares, SharesOutstanding{strings.TrimSpace(record[codeIndex]), n})
+	}
+	return date, shares, len(shares) > 0
+}
+
+func printSharesOutstanding(date string, shares []SharesOutstanding) {
+	log.Println(date)
+	for _, s := range shares {
+		fmt.Println(s.SecurityCode, s.Shares)
+	}
+}
+
+func writeSharesOutstanding(db *sql.DB, date string, shares []SharesOutstanding) bool {
+	sqlString := "INSERT INTO shares_outstanding (trade_date, security_code, shares) VALUES\n"
+	for _, s := range shares {
+		sqlString += fmt.Sprintf("('%s', '%s', '%s'),\n", date, s.SecurityCode, s.Shares)
+	}
+	sqlString = strings.TrimRight(sqlString, ",\n")
+	sqlString += "\nON CONFLICT (trade_date, security_code) DO UPDATE SET shares = EXCLUDED.shares;"
+	//fmt.Println(sqlString)
+	_, err := db.Exec(sqlString)
+	if err != nil {
+		log.Println("writeSharesOutstanding", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/transform"
+)
+
+var enc = traditionalchinese.Big5
+
+const urlOTCEmergingQuote = "http://www.tpex.org.tw/web/emergingstock/historical/daily/EMDaily_dl.php?l=zh-tw&f=EMdss006.%4d%02d%02d-C.csv"
+const kMinDate = 20000000
+
+const (
+	DB_USER     = "stock"
+	DB_PASSWORD = "test"
+	DB_NAME     = "stock"
+	DB_HOST     = "data.example.com"
+)
+
+const deleteSql = "DELETE FROM emerging_quotes WHERE trade_date = $1"
+
+var flagFromDate = flag.Int("f", 0, "from date YYYYMMDD (default: the day after latest trade date in DB)")
+var flagToDate = flag.Int("t", 0, "to date YYYYMMDD (default: today)")
+var flagLastTradeDay = flag.Bool("l", false, "last trade day only")
+
+func main() {
+	flag.Parse()
+	dbinfo := fmt.Sprintf("user=%s password=%s dbname=%s host=%s sslmode=disable", DB_USER, DB_PASSWORD, DB_NAME, DB_HOST)
+	db, err := sql.Open("postgres", dbinfo)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	today := time.Now()
+	fromDate := *flagFromDate
+	toDate := *flagToDate
+	if fromDate == 0 || fromDate < kMinDate {
+		fromDate, err = fetchLastTradeDate(db)
+		if err != nil {
+			fromDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+		}
+	}
+	if toDate == 0 || toDate < kMinDate {
+		toDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+	}
+
+	log.Println(fromDate, toDate)
+
+	local, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		panic(err)
+	}
+
+	var quoteDate, beginDate time.Time
+	if *flagLastTradeDay {
+		quoteDate = today
+	} else {
+		quoteDate = time.Date(toDate/10000, time.Month(toDate%10000/100), toDate%100, 1, 0, 0, 0, local)
+	}
+	beginDate = time.Date(fromDate/10000, time.Month(fromDate%10000/100), fromDate%100, 0, 0, 0, 0, local)
+	log.Println("beginDate = ", beginDate)
+	for quoteDate.After(beginDate) {
+		log.Println(quoteDate)
+		time.Sleep(1 * time.Second)
+		csvString, ok := fetchEmergingQuotes(quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day())
+		if ok {
+			tradeDate := fmt.Sprintf("%04d/%02d/%02d", quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day())
+			_, err := db.Exec(deleteSql, tradeDate)
+			if err == nil || err == sql.ErrNoRows {
+				//printEmergingQuotes(quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day(), csvString)
+				writeEmergingQuotes(db, quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day(), csvString)
+			} else {
+				log.Printf("DELETE error = %v\n", err)
+			}
+			if *flagLastTradeDay {
+				break
+			}
+		}
+		quoteDate = quoteDate.AddDate(0, 0, -1)
+	}
+}
+
+func fetchLastTradeDate(db *sql.DB) (int, error) {
+	var row1 string
+	sqlString := "SELECT to_char(MAX(trade_date)+interval '1 day', 'YYYYMMDD') FROM emerging_quotes"
+	err := db.QueryRow(sqlString).Scan(&row1)
+	if err != nil {
+		return 0, err
+	}
+	log.Println("the day after latest trade day = ", row1)
+
+	return strconv.Atoi(row1)
+}
+
+func fetchEmergingQuotes(year, month, day int) (string, bool) {
+	var url string
+
+	url = fmt.Sprintf(urlOTCEmergingQuote, year, month, day)
+	log.Println(url)
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Println(err)
+		return "", false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", false
+	}
+
+	log.Println("----")
+	log.Println(resp.StatusCode)
+	log.Println(resp.Status)
+
+	r := transform.NewReader(resp.Body, enc.NewDecoder())
+	input := bufio.NewScanner(r)
+
+	// title and date lines come first, then the header line starting with 代號
+	headerFound := false
+	lineCount := 0
+	out := ""
+	for input.Scan() {
+		in := strings.TrimSpace(input.Text())
+		if !headerFound {
+			headerFound = strings.HasPrefix(strings.Trim(in, "\""), "代號")
+			continue
+		}
+		if len(in) <= 20 {
+			break
+		}
+		lineCount++
+		out += in + "\n"
+	}
+
+	if lineCount < 1 {
+		return "", false
+	}
+
+	return out, true
+}
+
+func printEmergingQuotes(year, month, day int, csvString string) {
+	log.Println(year, month, day)
+
+	csvr := csv.NewReader(strings.NewReader(csvString))
+
+	records, err := csvr.ReadAll()
+	if err == nil {
+		for _, record := range records {
+			for _, field := range record {
+				field = strings.Replace(field, ",", "", -1)
+				fmt.Print(strings.TrimSpace(field))
+				fmt.Print("\t")
+			}
+			fmt.Println()
+		}
+	}
+}
+
+func writeEmergingQuotes(db *sql.DB, year, month, day int, csvString string) bool {
+	csvr := csv.NewReader(strings.NewReader(csvString))
+	csvr.FieldsPerRecord = -1
+
+	records, err := csvr.ReadAll()
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+
+	quoteDate := fmt.Sprintf("%04d/%02d/%02d", year, month, day)
+
+	// 代號,名稱,前日均價,報買價,報買量,報賣價,報賣量,日最高,日最低,日均價,成交,漲跌,成交量,成交金額,成交筆數
+	sqlString := "INSERT INTO emerging_quotes (trade_date, security_code, last_average_price, last_bid_price, last_bid_volume, last_ask_price, last_ask_volume, highest_price, lowest_price, average_price, close_price, trade_volume, trade_amount, trade_count) VALUES\n"
+
+	count := 0
+	for _, record := range records {
+		if len(record) < 15 {
+			continue
+		}
+		sqlString += fmt.Sprintf("('%s',", quoteDate)
+
+		for i, field := range record {
+			if i == 1 || i == 11 {
+				continue
+			}
+
+			field = strings.TrimSpace(field)
+			if strings.Contains(field, "--") || len(field) == 0 {
+				sqlString += " null"
+			} else {
+				sqlString += " '" + strings.Replace(field, ",", "", -1) + "'"
+			}
+			if i == 14 {
+				break
+			}
+			sqlString += ","
+		}
+		sqlString += "),\n"
+		count++
+	}
+	if count == 0 {
+		return true
+	}
+
+	sqlString = strings.TrimRight(sqlString, ",\n") + ";"
+	//fmt.Println(sqlString)
+	_, err = db.Exec(sqlString)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
@@ -1,39 +0,0 @@
-CREATE TABLE sectors (
-	id		integer,
-	name	varchar,
-	UNIQUE(id)
-);
-
-INSERT INTO sectors VALUES
-('01','水泥工業'),
-('02','食品工業'),
-('03','塑膠工業'),
-('04','紡織纖維'),
-('05','電機機械'),
-('06','電器電纜'),
-('07','化學生技醫療'),
-('21','化學工業'),
-('22','生技醫療業'),
-('08','玻璃陶瓷'),
-('09','造紙工業'),
-('10','鋼鐵工業'),
-('11','橡膠工業'),
-('12','汽車工業'),
-('13','電子工業'),
-('24','半導體業'),
-('25','電腦及週邊設備業'),
-('26','光電業'),
-('27','通信網路業'),
-('28','電子零組件業'),
-('29','電子通路業'),
-('30','資訊服務業'),
-('31','其他電子業'),
-('14','建材營造'),
-('15','航運業'),
-('16','觀光事業'),
-('17','金融保險'),
-('18','貿易百貨'),
-('9299','存託憑證'),
-('23','油電燃氣業'),
-('19','綜合'),
-('20','其他');
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/transform"
+)
+
+var enc = traditionalchinese.Big5
+
+const (
+	urlFuturesQuote    = "http://www.taifex.com.tw/cht/3/futDataDown"
+	urlFuturesInvestor = "http://www.taifex.com.tw/cht/3/futContractsDateDown"
+	urlOptionsInvestor = "http://www.taifex.com.tw/cht/3/callsAndPutsDateDown"
+	urlPutCallRatio    = "http://www.taifex.com.tw/cht/3/pcRatioDown"
+	kMinDate           = 20000000
+)
+
+const (
+	DB_USER     = "stock"
+	DB_PASSWORD = "test"
+	DB_NAME     = "stock"
+	DB_HOST     = "data.example.com"
+)
+
+// futures contracts for quotes and the matching commodity id for investors
+var futuresContracts = [...]string{"TX", "MTX"}
+var futuresCommodities = [...]string{"TXF", "MXF"}
+var optionsCommodities = [...]string{"TXO"}
+
+var investorTypes = map[string]string{
+	"自營商":   "dealer",
+	"投信":    "trust",
+	"外資":    "foreign",
+	"外資及陸資": "foreign",
+}
+
+var flagFromDate = flag.Int("f", 0, "from date YYYYMMDD (default: the day after latest trade date in DB)")
+var flagToDate = flag.Int("t", 0, "to date YYYYMMDD (default: today)")
+var flagLastTradeDay = flag.Bool("l", false, "last trade day only")
+
+func main() {
+	flag.Parse()
+	dbinfo := fmt.Sprintf("user=%s password=%s dbname=%s host=%s sslmode=disable", DB_USER, DB_PASSWORD, DB_NAME, DB_HOST)
+	db, err := sql.Open("postgres", dbinfo)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	today := time.Now()
+	fromDate := *flagFromDate
+	toDate := *flagToDate
+	if fromDate == 0 || fromDate < kMinDate {
+		fromDate, err = fetchLastTradeDate(db)
+		if err != nil {
+			fromDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+		}
+	}
+	if toDate == 0 || toDate < kMinDate {
+		toDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+	}
+
+	log.Println(fromDate, toDate)
+
+	local, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		panic(err)
+	}
+
+	var quoteDate, beginDate time.Time
+	if *flagLastTradeDay {
+		quoteDate = today
+	} else {
+		quoteDate = time.Date(toDate/10000, time.Month(toDate%10000/100), toDate%100, 1, 0, 0, 0, local)
+	}
+	beginDate = time.Date(fromDate/10000, time.Month(fromDate%10000/100), fromDate%100, 0, 0, 0, 0, local)
+	log.Println("beginDate = ", beginDate)
+	for quoteDate.After(beginDate) {
+		fmt.Println("-------")
+		fmt.Println(quoteDate)
+		dateString := fmt.Sprintf("%4d/%02d/%02d", quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day())
+
+		time.Sleep(1 * time.Second)
+		quotes, ok := fetchFuturesQuotes(dateString)
+		if !ok {
+			quoteDate = quoteDate.AddDate(0, 0, -1)
+			continue
+		}
+		time.Sleep(200 * time.Millisecond)
+		futuresInvestors, ok := fetchInvestors(urlFuturesInvestor, futuresCommodities[:], dateString)
+		if !ok {
+			quoteDate = quoteDate.AddDate(0, 0, -1)
+			continue
+		}
+		time.Sleep(200 * time.Millisecond)
+		optionsInvestors, ok := fetchInvestors(urlOptionsInvestor, optionsCommodities[:], dateString)
+		if !ok {
+			quoteDate = quoteDate.AddDate(0, 0, -1)
+			continue
+		}
+		time.Sleep(200 * time.Millisecond)
+		ratios, ok := fetchCSV(urlPutCallRatio, url.Values{"queryStartDate": {dateString}, "queryEndDate": {dateString}})
+		if !ok {
+			quoteDate = quoteDate.AddDate(0, 0, -1)
+			continue
+		}
+
+		writeFuturesQuotes(db, dateString, quotes)
+		writeFuturesInvestors(db, dateString, futuresInvestors)
+		writeOptionsInvestors(db, dateString, optionsInvestors)
+		writePutCallRatio(db, dateString, ratios)
+
+		if *flagLastTradeDay {
+			break
+		}
+		quoteDate = quoteDate.AddDate(0, 0, -1)
+	}
+}
+
+func fetchLastTradeDate(db *sql.DB) (int, error) {
+	var row1 string
+	sqlString := "SELECT to_char(MAX(trade_date)+interval '1 day', 'YYYYMMDD') FROM futures_quotes"
+	err := db.QueryRow(sqlString).Scan(&row1)
+	if err != nil {
+		return 0, err
+	}
+	log.Println("the day after latest trade day = ", row1)
+
+	return strconv.Atoi(row1)
+}
+
+// fetchCSV posts the query form and returns the Big5 CSV records without
+// the header line.
+func fetchCSV(url string, form url.Values) ([][]string, bool) {
+	log.Println(url, form.Encode())
+	resp, err := http.PostForm(url, form)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+
+	log.Println("----")
+	log.Println(resp.StatusCode)
+	log.Println(resp.Status)
+
+	csvr := csv.NewReader(transform.NewReader(resp.Body, enc.NewDecoder()))
+	csvr.FieldsPerRecord = -1
+	csvr.LazyQuotes = true
+	records, err := csvr.ReadAll()
+	if err != nil {
+		// no trade day returns an html page
+		log.Println(err)
+		return nil, false
+	}
+
+	if len(records) < 2 {
+		return nil, false
+	}
+
+	return records[1:], true
+}
+
+func fetchFuturesQuotes(dateString string) ([][]string, bool) {
+	var quotes [][]string
+	for _, contract := range futuresContracts {
+		form := url.Values{}
+		form.Set("down_type", "1")
+		form.Set("commodity_id", contract)
+		form.Set("queryStartDate", dateString)
+		form.Set("queryEndDate", dateString)
+		records, ok := fetchCSV(urlFuturesQuote, form)
+		if !ok {
+			return nil, false
+		}
+		quotes = append(quotes, records...)
+	}
+	return quotes, true
+}
+
+func fetchInvestors(url string, commodities []string, dateString string) ([][]string, bool) {
+	var investors [][]string
+	for _, commodity := range commodities {
+		form := urlValues(commodity, dateString)
+		records, ok := fetchCSV(url, form)
+		if !ok {
+			return nil, false
+		}
+		investors = append(investors, records...)
+	}
+	return investors, true
+}
+
+func urlValues(commodity, dateString string) url.Values {
+	form := url.Values{}
+	form.Set("queryStartDate", dateString)
+	form.Set("queryEndDate", dateString)
+	form.Set("commodityId", commodity)
+	return form
+}
+
+func sqlValue(s string) string {
+	s = strings.Replace(strings.TrimSpace(s), ",", "", -1)
+	if s == "-" || strings.Contains(s, "--") || len(s) == 0 {
+		return "null"
+	}
+	return "'" + s + "'"
+}
+
+func writeFuturesQuotes(db *sql.DB, date string, quotes [][]string) bool {
+	sqlString := "DELETE FROM futures_quotes WHERE trade_date='" + date + "';"
+	_, err := db.Exec(sqlString)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("DELETE error = %v\n", err)
+		return false
+	}
+
+	// 交易日期,契約,到期月份(週別),開盤價,最高價,最低價,收盤價,漲跌價,漲跌%,成交量,結算價,未沖銷契約數,最後最佳買價,最後最佳賣價,歷史最高價,歷史最低價,是否因訊息面暫停交易,交易時段
+	sqlString = "INSERT INTO futures_quotes (trade_date, contract, expiry_month, trade_session, open_price, highest_price, lowest_price, close_price, trade_volume, settlement_price, open_interest) VALUES\n"
+	count := 0
+	for _, quote := range quotes {
+		if len(quote) < 18 || strings.Contains(quote[2], "/") {
+			// spread contracts
+			continue
+		}
+		session := "regular"
+		if strings.TrimSpace(quote[17]) == "盤後" {
+			session = "after_hours"
+		}
+		sqlString += fmt.Sprintf("('%s', '%s', '%s', '%s', %s, %s, %s, %s, %s, %s, %s),\n", date,
+			strings.TrimSpace(quote[1]), strings.TrimSpace(quote[2]), session,
+			sqlValue(quote[3]), sqlValue(quote[4]), sqlValue(quote[5]), sqlValue(quote[6]),
+			sqlValue(quote[9]), sqlValue(quote[10]), sqlValue(quote[11]))
+		count++
+	}
+	if count == 0 {
+		return true
+	}
+	sqlString = strings.TrimRight(sqlString, ",\n") + ";"
+	//fmt.Println(sqlString)
+	_, err = db.Exec(sqlString)
+	if err != nil {
+		log.Println("writeFuturesQuotes", err)
+		return false
+	}
+	return true
+}
+
+// investorValues returns the twelve trade and open interest columns starting at column start.
+func investorValues(record []string, start int) string {
+	values := ""
+	for i := start; i < start+12; i++ {
+		values += sqlValue(record[i])
+		if i != start+11 {
+			values += ", "
+		}
+	}
+	return values
+}
+
+func writeFuturesInvestors(db *sql.DB, date string, investors [][]string) bool {
+	sqlString := "DELETE FROM futures_investors WHERE trade_date='" + date + "';"
+	_, err := db.Exec(sqlString)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("DELETE error = %v\n", err)
+		return false
+	}
+
+	// 日期,商品名稱,身份別,多方交易口數,多方交易契約金額(千元),空方交易口數,空方交易契約金額(千元),多空交易口數淨額,多空交易契約金額淨額(千元),多方未平倉口數,多方未平倉契約金額(千元),空方未平倉口數,空方未平倉契約金額(千元),多空未平倉口數淨額,多空未平倉契約金額淨額(千元)
+	sqlString = "INSERT INTO futures_investors (trade_date, contract, investor_type, long_volume, long_amount, short_volume, short_amount, net_volume, net_amount, long_oi, long_oi_amount, short_oi, short_oi_amount, net_oi, net_oi_amount) VALUES\n"
+	count := 0
+	for _, investor := range investors {
+		if len(investor) < 15 {
+			continue
+		}
+		investorType, ok := investorTypes[strings.TrimSpace(investor[2])]
+		if !ok {
+			continue
+		}
+		sqlString += fmt.Sprintf("('%s', '%s', '%s', %s),\n", date, strings.TrimSpace(investor[1]), investorType, investorValues(investor, 3))
+		count++
+	}
+	if count == 0 {
+		return true
+	}
+	sqlString = strings.TrimRight(sqlString, ",\n") + ";"
+	//fmt.Println(sqlString)
+	_, err = db.Exec(sqlString)
+	if err != nil {
+		log.Println("writeFuturesInvestors", err)
+		return false
+	}
+	return true
+}
+
+func writeOptionsInvestors(db *sql.DB, date string, investors [][]string) bool {
+	sqlString := "DELETE FROM options_investors WHERE trade_date='" + date + "';"
+	_, err := db.Exec(sqlString)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("DELETE error = %v\n", err)
+		return false
+	}
+
+	// 日期,商品名稱,買賣權別,身份別,買方交易口數,買方交易契約金額(千元),賣方交易口數,賣方交易契約金額(千元),交易口數買賣淨額,交易契約金額買賣淨額(千元),買方未平倉口數,買方未平倉契約金額(千元),賣方未平倉口數,賣方未平倉契約金額(千元),未平倉口數買賣淨額,未平倉契約金額買賣淨額(千元)
+	sqlString = "INSERT INTO options_investors (trade_date, contract, call_put, investor_type, long_volume, long_amount, short_volume, short_amount, net_volume, net_amount, long_oi, long_oi_amount, short_oi, short_oi_amount, net_oi, net_oi_amount) VALUES\n"
+	count := 0
+	for _, investor := range investors {
+		if len(investor) < 16 {
+			continue
+		}
+		investorType, ok := investorTypes[strings.TrimSpace(investor[3])]
+		if !ok {
+			continue
+		}
+		callPut := "call"
+		if strings.TrimSpace(investor[2]) == "賣權" {
+			callPut = "put"
+		}
+		sqlString += fmt.Sprintf("('%s', '%s', '%s', '%s', %s),\n", date, strings.TrimSpace(investor[1]), callPut, investorType, investorValues(investor, 4))
+		count++
+	}
+	if count == 0 {
+		return true
+	}
+	sqlString = strings.TrimRight(sqlString, ",\n") + ";"
+	//fmt.Println(sqlString)
+	_, err = db.Exec(sqlString)
+	if err != nil {
+		log.Println("writeOptionsInvestors", err)
+		return false
+	}
+	return true
+}
+
+func writePutCallRatio(db *sql.DB, date string, ratios [][]string) bool {
+	sqlString := "DELETE FROM put_call_ratios WHERE trade_date='" + date + "';"
+	_, err := db.Exec(sqlString)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("DELETE error = %v\n", err)
+		return false
+	}
+
+	// 日期,賣權成交量,買權成交量,買賣權成交量比率%,賣權未平倉量,買權未平倉量,買賣權未平倉量比率%
+	for _, ratio := range ratios {
+		if len(ratio) < 7 {
+			continue
+		}
+		sqlString = fmt.Sprintf("INSERT INTO put_call_ratios (trade_date, put_volume, call_volume, volume_ratio, put_oi, call_oi, oi_ratio) VALUES ('%s', %s, %s, %s, %s, %s, %s);", date,
+			sqlValue(ratio[1]), sqlValue(ratio[2]), sqlValue(ratio[3]), sqlValue(ratio[4]), sqlValue(ratio[5]), sqlValue(ratio[6]))
+		//fmt.Println(sqlString)
+		_, err = db.Exec(sqlString)
+		if err != nil {
+			log.Println("writePutCallRatio", err)
+			return false
+		}
+		break
+	}
+	return true
+}
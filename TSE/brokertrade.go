@@ -0,0 +1,557 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/transform"
+)
+
+var enc = traditionalchinese.Big5
+
+// bsr only publishes the latest trade day, run it after market close.
+const urlTSEBrokerMenu = "http://bsr.twse.com.tw/bshtm/bsMenu.aspx"
+const urlTSEBrokerBase = "http://bsr.twse.com.tw/bshtm/"
+const urlTSEBrokerContent = "http://bsr.twse.com.tw/bshtm/bsContent.aspx?v=t"
+const urlTSEBrokerPage = "http://bsr.twse.com.tw/bshtm/bsContent.aspx"
+
+// TPEx also publishes the latest trade day only, behind its own captcha.
+const urlOTCBrokerTrade = "http://www.tpex.org.tw/web/stock/aftertrading/broker_trading/brokerBS.php?l=zh-tw"
+const urlOTCBrokerBase = "http://www.tpex.org.tw"
+
+// listed securities by market, strMode 2 is TSE and 4 is OTC
+const urlISINList = "http://isin.twse.com.tw/isin/C_public.jsp?strMode=%d"
+const kMaxRetry = 3
+
+const (
+	DB_USER     = "stock"
+	DB_PASSWORD = "test"
+	DB_NAME     = "stock"
+	DB_HOST     = "data.example.com"
+)
+
+var errCaptcha = errors.New("captcha rejected")
+var errNoData = errors.New("no data")
+
+// CaptchaSolver returns the text shown in a captcha image.
+type CaptchaSolver interface {
+	Solve(image []byte) (string, error)
+}
+
+// consoleSolver saves the captcha image and asks for its text on stdin.
+type consoleSolver struct {
+	file  string
+	input *bufio.Reader
+}
+
+func (s *consoleSolver) Solve(image []byte) (string, error) {
+	if err := ioutil.WriteFile(s.file, image, 0644); err != nil {
+		return "", err
+	}
+	fmt.Printf("captcha saved to %s, enter text: ", s.file)
+	text, err := s.input.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(text), nil
+}
+
+// commandSolver runs a command with the captcha image on stdin and reads its
+// text from stdout, for an OCR script or a solving service without anyone
+// at the console.
+type commandSolver struct {
+	command string
+}
+
+func (s *commandSolver) Solve(image []byte) (string, error) {
+	cmd := exec.Command("sh", "-c", s.command)
+	cmd.Stdin = strings.NewReader(string(image))
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+type BrokerTrade struct {
+	BrokerCode string
+	BrokerName string
+	Price      string
+	Buy        int64
+	Sell       int64
+}
+
+type brokerTradeKey struct {
+	brokerCode string
+	price      string
+}
+
+var flagCodes = flag.String("s", "", "security codes separated by comma (default: all listed stocks of the market)")
+var flagMarket = flag.String("m", "tse", "market: tse or otc")
+var flagCaptchaFile = flag.String("c", "captcha.png", "file to save captcha image for the console solver")
+var flagSolver = flag.String("solver", "", "command reading a captcha image on stdin and writing its text to stdout (default: ask on the console for every code)")
+
+func main() {
+	flag.Parse()
+	dbinfo := fmt.Sprintf("user=%s password=%s dbname=%s host=%s sslmode=disable", DB_USER, DB_PASSWORD, DB_NAME, DB_HOST)
+	db, err := sql.Open("postgres", dbinfo)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fetch := fetchBrokerTrades
+	mode := 2
+	switch *flagMarket {
+	case "tse":
+	case "otc":
+		fetch = fetchOTCBrokerTrades
+		mode = 4
+	default:
+		log.Println("unknown market", *flagMarket)
+		return
+	}
+
+	var codes []string
+	if len(*flagCodes) > 0 {
+		codes = strings.Split(*flagCodes, ",")
+	} else {
+		codes, err = readSecurityCodes(mode)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+	}
+	log.Println(*flagMarket, len(codes))
+
+	// every code takes one captcha, the console solver asks for each of
+	// them, use -solver to run unattended
+	var solver CaptchaSolver = &consoleSolver{file: *flagCaptchaFile, input: bufio.NewReader(os.Stdin)}
+	if len(*flagSolver) > 0 {
+		solver = &commandSolver{command: *flagSolver}
+	}
+	for _, code := range codes {
+		code = strings.TrimSpace(code)
+		var trades []BrokerTrade
+		var tradeDate string
+		for retry := 0; retry < kMaxRetry; retry++ {
+			time.Sleep(1 * time.Second)
+			trades, tradeDate, err = fetch(code, solver)
+			if err != errCaptcha {
+				break
+			}
+			log.Println(code, err)
+		}
+		if err != nil {
+			log.Println(code, err)
+			continue
+		}
+		log.Println(code, tradeDate, len(trades))
+		//printBrokerTrades(code, trades)
+		writeBrokers(db, trades)
+		writeBrokerTrades(db, tradeDate, code, trades)
+	}
+}
+
+// readSecurityCodes returns the 4 digit codes listed in a market of the ISIN
+// list, each row starts with "2330　台積電".
+func readSecurityCodes(mode int) ([]string, error) {
+	resp, err := http.Get(fmt.Sprintf(urlISINList, mode))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+	doc, err := html.Parse(transform.NewReader(resp.Body, enc.NewDecoder()))
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "td" && n.FirstChild != nil && n.FirstChild.Type == html.TextNode {
+			fields := strings.FieldsFunc(n.FirstChild.Data, func(r rune) bool { return r == '　' || r == ' ' })
+			if len(fields) > 0 && stockCode.MatchString(fields[0]) {
+				codes = append(codes, fields[0])
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	if len(codes) == 0 {
+		return nil, errors.New("no listed code")
+	}
+	return codes, nil
+}
+
+var stockCode = regexp.MustCompile(`^[0-9]{4}$`)
+
+// 2018/06/29 on bsr, 107/06/29 on TPEx
+var reportDate = regexp.MustCompile(`([0-9]{3,4})/([0-9]{2})/([0-9]{2})`)
+
+// findTradeDate returns the first date of a report page as YYYY/MM/DD, the
+// trade date the exchange reports rather than the day of the crawl.
+func findTradeDate(contents string) (string, bool) {
+	m := reportDate.FindStringSubmatch(contents)
+	if m == nil {
+		return "", false
+	}
+	year, _ := strconv.Atoi(m[1])
+	if year < 1911 {
+		year += 1911
+	}
+	return fmt.Sprintf("%d/%s/%s", year, m[2], m[3]), true
+}
+
+// fetchBrokerTrades walks the bsr form: read hidden fields and captcha from
+// the menu page, post the security code with the solved captcha, then
+// download the CSV content of the result.  The trade date is the one of the
+// content page.
+func fetchBrokerTrades(code string, solver CaptchaSolver) ([]BrokerTrade, string, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, "", err
+	}
+	client := &http.Client{Jar: jar}
+
+	form, captcha, err := solveForm(client, urlTSEBrokerMenu, urlTSEBrokerBase, "CaptchaImage", solver)
+	if err != nil {
+		return nil, "", err
+	}
+
+	form.Set("RadioButton_Normal", "RadioButton_Normal")
+	form.Set("TextBox_Stkno", code)
+	form.Set("CaptchaControl1", captcha)
+	form.Set("btnOK", "查詢")
+	resp, err := client.PostForm(urlTSEBrokerMenu, form)
+	if err != nil {
+		return nil, "", err
+	}
+	contents, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, "", err
+	}
+	if strings.Contains(string(contents), "驗證碼錯誤") {
+		return nil, "", errCaptcha
+	}
+	if strings.Contains(string(contents), "查無資料") {
+		return nil, "", errNoData
+	}
+
+	page, err := getContents(client, urlTSEBrokerPage)
+	if err != nil {
+		return nil, "", err
+	}
+	tradeDate, ok := findTradeDate(page)
+	if !ok {
+		return nil, "", errors.New("trade date not found")
+	}
+
+	csvContents, err := getContents(client, urlTSEBrokerContent)
+	if err != nil {
+		return nil, "", err
+	}
+	trades, err := parseBrokerTrades(strings.NewReader(csvContents))
+	return trades, tradeDate, err
+}
+
+// fetchOTCBrokerTrades walks the TPEx form the same way, its result page
+// shows the 資料日期 and links the CSV download.
+func fetchOTCBrokerTrades(code string, solver CaptchaSolver) ([]BrokerTrade, string, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, "", err
+	}
+	client := &http.Client{Jar: jar}
+
+	form, captcha, err := solveForm(client, urlOTCBrokerTrade, urlOTCBrokerBase, "captcha", solver)
+	if err != nil {
+		return nil, "", err
+	}
+
+	form.Set("stk_code", code)
+	form.Set("auth", captcha)
+	resp, err := client.PostForm(urlOTCBrokerTrade, form)
+	if err != nil {
+		return nil, "", err
+	}
+	contents, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, "", err
+	}
+	if strings.Contains(string(contents), "驗證碼") && strings.Contains(string(contents), "錯誤") {
+		return nil, "", errCaptcha
+	}
+	if strings.Contains(string(contents), "查無") {
+		return nil, "", errNoData
+	}
+
+	doc, err := html.Parse(strings.NewReader(string(contents)))
+	if err != nil {
+		return nil, "", err
+	}
+	downloadURL := ""
+	findLink(doc, "download", &downloadURL)
+	if len(downloadURL) == 0 {
+		return nil, "", errors.New("download link not found")
+	}
+	i := strings.Index(string(contents), "資料日期")
+	if i < 0 {
+		return nil, "", errors.New("trade date not found")
+	}
+	tradeDate, ok := findTradeDate(string(contents)[i:])
+	if !ok {
+		return nil, "", errors.New("trade date not found")
+	}
+
+	if !strings.HasPrefix(downloadURL, "http") {
+		downloadURL = urlOTCBrokerBase + "/" + strings.TrimPrefix(downloadURL, "/")
+	}
+	csvContents, err := getContents(client, downloadURL)
+	if err != nil {
+		return nil, "", err
+	}
+	trades, err := parseBrokerTrades(strings.NewReader(csvContents))
+	return trades, tradeDate, err
+}
+
+// solveForm reads the hidden fields and the captcha of a form page, the
+// image source is relative to base.
+func solveForm(client *http.Client, page, base, captchaMark string, solver CaptchaSolver) (url.Values, string, error) {
+	resp, err := client.Get(page)
+	if err != nil {
+		return nil, "", err
+	}
+	doc, err := html.Parse(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, "", err
+	}
+
+	form := url.Values{}
+	captchaURL := ""
+	formFields(doc, captchaMark, form, &captchaURL)
+	if len(captchaURL) == 0 {
+		return nil, "", errors.New("captcha image not found")
+	}
+	if !strings.HasPrefix(captchaURL, "http") {
+		captchaURL = strings.TrimRight(base, "/") + "/" + strings.TrimPrefix(captchaURL, "/")
+	}
+
+	resp, err = client.Get(captchaURL)
+	if err != nil {
+		return nil, "", err
+	}
+	image, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, "", err
+	}
+
+	captcha, err := solver.Solve(image)
+	if err != nil {
+		return nil, "", err
+	}
+	return form, captcha, nil
+}
+
+// getContents returns a page decoded from Big5.
+func getContents(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(resp.Status)
+	}
+	contents, err := ioutil.ReadAll(transform.NewReader(resp.Body, enc.NewDecoder()))
+	if err != nil {
+		return "", err
+	}
+	return string(contents), nil
+}
+
+// formFields collects hidden inputs and the source of the captcha image,
+// the one containing captchaMark.
+func formFields(n *html.Node, captchaMark string, form url.Values, captchaURL *string) {
+	if n.Type == html.ElementNode {
+		attrs := make(map[string]string)
+		for _, a := range n.Attr {
+			attrs[a.Key] = a.Val
+		}
+		if n.Data == "input" && attrs["type"] == "hidden" {
+			form.Set(attrs["name"], attrs["value"])
+		}
+		if n.Data == "img" && strings.Contains(attrs["src"], captchaMark) {
+			*captchaURL = strings.TrimPrefix(attrs["src"], "./")
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		formFields(c, captchaMark, form, captchaURL)
+	}
+}
+
+// findLink returns the first href containing mark.
+func findLink(n *html.Node, mark string, link *string) {
+	if len(*link) > 0 {
+		return
+	}
+	if n.Type == html.ElementNode && n.Data == "a" {
+		for _, a := range n.Attr {
+			if a.Key == "href" && strings.Contains(a.Val, mark) {
+				*link = a.Val
+				return
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		findLink(c, mark, link)
+	}
+}
+
+// 序號,證券商,成交單價,買進股數,賣出股數,,序號,證券商,成交單價,買進股數,賣出股數
+// each line carries two records side by side
+func parseBrokerTrades(r io.Reader) ([]BrokerTrade, error) {
+	csvr := csv.NewReader(r)
+	csvr.FieldsPerRecord = -1
+
+	records, err := csvr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []brokerTradeKey
+	trades := make(map[brokerTradeKey]*BrokerTrade)
+	for _, record := range records {
+		for start := 0; start+5 <= len(record); start += 6 {
+			trade, ok := brokerTrade(record[start : start+5])
+			if !ok {
+				continue
+			}
+			key := brokerTradeKey{trade.BrokerCode, trade.Price}
+			if t, ok := trades[key]; ok {
+				t.Buy += trade.Buy
+				t.Sell += trade.Sell
+			} else {
+				keys = append(keys, key)
+				trades[key] = &trade
+			}
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, errNoData
+	}
+
+	var out []BrokerTrade
+	for _, key := range keys {
+		out = append(out, *trades[key])
+	}
+	return out, nil
+}
+
+func brokerTrade(fields []string) (BrokerTrade, bool) {
+	var trade BrokerTrade
+	var seq int
+	if _, err := fmt.Sscanf(strings.TrimSpace(fields[0]), "%d", &seq); err != nil {
+		// header or title line
+		return trade, false
+	}
+	broker := []rune(strings.TrimSpace(fields[1]))
+	if len(broker) < 4 {
+		return trade, false
+	}
+	trade.BrokerCode = string(broker[:4])
+	trade.BrokerName = strings.Map(func(r rune) rune {
+		if r == '　' || r == ' ' {
+			return -1
+		}
+		return r
+	}, string(broker[4:]))
+	trade.Price = strings.Replace(strings.TrimSpace(fields[2]), ",", "", -1)
+	fmt.Sscanf(strings.Replace(strings.TrimSpace(fields[3]), ",", "", -1), "%d", &trade.Buy)
+	fmt.Sscanf(strings.Replace(strings.TrimSpace(fields[4]), ",", "", -1), "%d", &trade.Sell)
+	return trade, true
+}
+
+func printBrokerTrades(code string, trades []BrokerTrade) {
+	log.Println(code)
+	for _, trade := range trades {
+		fmt.Println(trade.BrokerCode, trade.BrokerName, trade.Price, trade.Buy, trade.Sell)
+	}
+}
+
+func writeBrokers(db *sql.DB, trades []BrokerTrade) bool {
+	names := make(map[string]string)
+	for _, trade := range trades {
+		names[trade.BrokerCode] = trade.BrokerName
+	}
+
+	sqlString := "INSERT INTO brokers (broker_code, broker_name) VALUES\n"
+	for code, name := range names {
+		sqlString += fmt.Sprintf("('%s', '%s'),\n", code, strings.Replace(name, "'", "''", -1))
+	}
+	sqlString = strings.TrimRight(sqlString, ",\n")
+	sqlString += "\nON CONFLICT (broker_code) DO UPDATE SET broker_name = EXCLUDED.broker_name;"
+	_, err := db.Exec(sqlString)
+	if err != nil {
+		log.Println("writeBrokers", err)
+		return false
+	}
+	return true
+}
+
+func writeBrokerTrades(db *sql.DB, tradeDate, code string, trades []BrokerTrade) bool {
+	sqlString := fmt.Sprintf("DELETE FROM broker_trades WHERE trade_date='%s' AND security_code='%s';", tradeDate, code)
+	_, err := db.Exec(sqlString)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("DELETE error = %v\n", err)
+		return false
+	}
+
+	sqlString = "INSERT INTO broker_trades (trade_date, security_code, broker_code, price, buy_volume, sell_volume) VALUES\n"
+	for _, trade := range trades {
+		sqlString += fmt.Sprintf("('%s', '%s', '%s', '%s', '%d', '%d'),\n", tradeDate, code, trade.BrokerCode, trade.Price, trade.Buy, trade.Sell)
+	}
+	sqlString = strings.TrimRight(sqlString, ",\n") + ";"
+	//fmt.Println(sqlString)
+	_, err = db.Exec(sqlString)
+	if err != nil {
+		log.Println("writeBrokerTrades", err)
+		return false
+	}
+	return true
+}
@@ -44,6 +44,19 @@ type jsonContent2 struct {
 	Data   [][]string `json:"creditList"`
 }
 
+type IndexValue struct {
+	Open  string
+	High  string
+	Low   string
+	Close string
+}
+
+type IndexTrade struct {
+	Volume string
+	Amount string
+	Count  string
+}
+
 type Investor struct {
 	Buy        string
 	Sell       string
@@ -76,6 +89,8 @@ type IndexMarginShort struct {
 var flagFromDate = flag.Int("f", 0, "from date YYYYMMDD (default: the day after latest trade date in DB)")
 var flagToDate = flag.Int("t", 0, "to date YYYYMMDD (default: today)")
 var flagLastTradeDay = flag.Bool("l", false, "last trade day only")
+var flagMonthly = flag.Bool("m", false, "monthly bulk mode, fetch index values and trades once per month")
+var flagMonthlyDaily = flag.Bool("md", false, "with -m, also fetch the investors and margin reports of every trade day")
 
 func main() {
 	flag.Parse()
@@ -119,6 +134,10 @@ func main() {
 	}
 	beginDate = time.Date(fromDate/10000, time.Month(fromDate%10000/100), fromDate%100, 0, 0, 0, 0, local)
 	log.Println("beginDate = ", beginDate)
+	if *flagMonthly {
+		fetchMonthly(db, beginDate, quoteDate)
+		return
+	}
 	for quoteDate.After(beginDate) {
 		fmt.Println("-------")
 		fmt.Println(quoteDate)
@@ -180,29 +199,80 @@ func fetchLastTradeDate(db *sql.DB) (int, error) {
 	return strconv.Atoi(row1)
 }
 
-func fetchIndexValue(year int, month int, day int) (string, string, string, string, bool) {
-	var url string
+// fetchMonthly fetches MI_5MINS_HIST and FMTQIK once per month and writes
+// every trade day between beginDate and endDate into index_values.  The
+// monthly BFI82U is a total of the month and MI_MARGN has no monthly report,
+// so index_investors and index_margin_short take two requests per trade day
+// and are only written with -md.
+func fetchMonthly(db *sql.DB, beginDate, endDate time.Time) {
+	month := time.Date(endDate.Year(), endDate.Month(), 1, 0, 0, 0, 0, endDate.Location())
+	for month.AddDate(0, 1, 0).After(beginDate) {
+		fmt.Println("-------")
+		fmt.Println(month.Year(), int(month.Month()))
+		time.Sleep(1 * time.Second)
+		values, ok := fetchIndexValues(month.Year(), int(month.Month()))
+		time.Sleep(200 * time.Millisecond)
+		trades, ok2 := fetchIndexTrades(month.Year(), int(month.Month()))
+		if !ok || !ok2 {
+			month = month.AddDate(0, -1, 0)
+			continue
+		}
+
+		for quoteDate := month.AddDate(0, 1, -1); quoteDate.Month() == month.Month(); quoteDate = quoteDate.AddDate(0, 0, -1) {
+			if quoteDate.After(endDate) || quoteDate.Before(beginDate) {
+				continue
+			}
+			dateTW := fmt.Sprintf("%3d/%02d/%02d", quoteDate.Year()-1911, int(quoteDate.Month()), quoteDate.Day())
+			value, ok := values[dateTW]
+			trade, ok2 := trades[dateTW]
+			if !ok || !ok2 {
+				continue
+			}
+			dateString := fmt.Sprintf("%4d%02d%02d", quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day())
+			writeIndexQuote(db, dateString, value.Open, value.High, value.Low, value.Close, trade.Volume, trade.Amount, trade.Count)
+			if !*flagMonthlyDaily {
+				continue
+			}
+
+			time.Sleep(200 * time.Millisecond)
+			indexInvestor, ok := fetchIndexInvestor(quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day())
+			if !ok {
+				log.Println(indexInvestor)
+				continue
+			}
+			time.Sleep(200 * time.Millisecond)
+			indexMarginShort, ok := fetchIndexMarginShort(quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day())
+			if !ok {
+				log.Println(indexMarginShort)
+				continue
+			}
+			writeIndexInvestor(db, dateString, indexInvestor)
+			writeIndexMarginShort(db, dateString, indexMarginShort)
+		}
+		month = month.AddDate(0, -1, 0)
+	}
+}
+
+func fetchMonthData(url string) ([][]string, bool) {
 	var contents []byte
-	var o, h, l, c string
 	var jsonData jsonContent
 
-	url = fmt.Sprintf(urlTSEIndexValue, year, month, day)
 	log.Println(url)
 	resp, err := http.Get(url)
 	if err != nil {
 		log.Println(err)
-		return o, h, l, c, false
+		return nil, false
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return o, h, l, c, false
+		return nil, false
 	}
 
 	contents, err = ioutil.ReadAll(resp.Body)
 	if err != nil {
 		log.Println(err)
-		return o, h, l, c, false
+		return nil, false
 	}
 
 	log.Println("----")
@@ -213,88 +283,80 @@ func fetchIndexValue(year int, month int, day int) (string, string, string, stri
 	err = json.Unmarshal(contents, &jsonData)
 	if err != nil {
 		log.Println("json unmarshal: ", err)
-		return o, h, l, c, false
+		return nil, false
 	}
 
 	if jsonData.Status != "OK" {
 		log.Println("stat: ", jsonData.Status)
-		return o, h, l, c, false
+		return nil, false
 	}
 
-	dateTW := fmt.Sprintf("%3d/%02d/%02d", year-1911, month, day)
-	//fmt.Println(dateTW)
-	for _, data := range jsonData.Data {
-		for i := 0; i < len(data); i++ {
-			if data[0] == dateTW {
-				//fmt.Println(data)
-				o = strings.Replace(data[1], ",", "", -1)
-				h = strings.Replace(data[2], ",", "", -1)
-				l = strings.Replace(data[3], ",", "", -1)
-				c = strings.Replace(data[4], ",", "", -1)
-				break
-			}
-		}
-	}
-
-	return o, h, l, c, true
+	return jsonData.Data, true
 }
 
-func fetchIndexTrade(year int, month int, day int) (string, string, string, bool) {
-	var url string
-	var contents []byte
-	var volume, amount, count string
-	var jsonData jsonContent
-
-	url = fmt.Sprintf(urlTSEIndexTrade, year, month, day)
-	log.Println(url)
-	resp, err := http.Get(url)
-	if err != nil {
-		log.Println(err)
-		return volume, amount, count, false
+// fetchIndexValues returns TAIEX open/high/low/close of the month keyed by ROC date (107/01/02).
+func fetchIndexValues(year int, month int) (map[string]IndexValue, bool) {
+	data, ok := fetchMonthData(fmt.Sprintf(urlTSEIndexValue, year, month, 1))
+	if !ok {
+		return nil, false
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return volume, amount, count, false
-	}
+	values := make(map[string]IndexValue)
+	for _, row := range data {
+		if len(row) < 5 {
+			continue
+		}
+		var value IndexValue
+		value.Open = strings.Replace(row[1], ",", "", -1)
+		value.High = strings.Replace(row[2], ",", "", -1)
+		value.Low = strings.Replace(row[3], ",", "", -1)
+		value.Close = strings.Replace(row[4], ",", "", -1)
+		values[row[0]] = value
+	}
+	return values, true
+}
 
-	contents, err = ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Println(err)
-		return volume, amount, count, false
+// fetchIndexTrades returns market volume/amount/count of the month keyed by ROC date.
+func fetchIndexTrades(year int, month int) (map[string]IndexTrade, bool) {
+	data, ok := fetchMonthData(fmt.Sprintf(urlTSEIndexTrade, year, month, 1))
+	if !ok {
+		return nil, false
 	}
 
-	log.Println("----")
-	log.Println(resp.StatusCode)
-	log.Println(resp.Status)
-	log.Println("Body len = ", len(contents))
-
-	err = json.Unmarshal(contents, &jsonData)
-	if err != nil {
-		log.Println("json unmarshal: ", err)
-		return volume, amount, count, false
+	trades := make(map[string]IndexTrade)
+	for _, row := range data {
+		if len(row) < 4 {
+			continue
+		}
+		var trade IndexTrade
+		trade.Volume = strings.Replace(row[1], ",", "", -1)
+		trade.Amount = strings.Replace(row[2], ",", "", -1)
+		trade.Count = strings.Replace(row[3], ",", "", -1)
+		trades[row[0]] = trade
 	}
+	return trades, true
+}
 
-	if jsonData.Status != "OK" {
-		log.Println("stat: ", jsonData.Status)
-		return volume, amount, count, false
+func fetchIndexValue(year int, month int, day int) (string, string, string, string, bool) {
+	values, ok := fetchIndexValues(year, month)
+	if !ok {
+		return "", "", "", "", false
 	}
 
 	dateTW := fmt.Sprintf("%3d/%02d/%02d", year-1911, month, day)
-	//fmt.Println(dateTW)
-	for _, data := range jsonData.Data {
-		for i := 0; i < len(data); i++ {
-			if data[0] == dateTW {
-				//fmt.Println(data)
-				volume = strings.Replace(data[1], ",", "", -1)
-				amount = strings.Replace(data[2], ",", "", -1)
-				count = strings.Replace(data[3], ",", "", -1)
-				break
-			}
-		}
+	value := values[dateTW]
+	return value.Open, value.High, value.Low, value.Close, true
+}
+
+func fetchIndexTrade(year int, month int, day int) (string, string, string, bool) {
+	trades, ok := fetchIndexTrades(year, month)
+	if !ok {
+		return "", "", "", false
 	}
 
-	return volume, amount, count, true
+	dateTW := fmt.Sprintf("%3d/%02d/%02d", year-1911, month, day)
+	trade := trades[dateTW]
+	return trade.Volume, trade.Amount, trade.Count, true
 }
 
 func fetchIndexInvestor(year int, month int, day int) (IndexInvestor, bool) {
@@ -460,7 +522,7 @@ func writeIndexQuote(db *sql.DB, date, o, h, l, c, volume, amount, count string)
 		return false
 	}
 
-	sqlString = fmt.Sprintf("INSERT INTO index_values VALUES ('%s', 'TAIEX', '%s', '%s', '%s', '%s', '%s', '%s', '%s');",
+	sqlString = fmt.Sprintf("INSERT INTO index_values (trade_date, index_code, open_value, highest_value, lowest_value, close_value, trade_volume, trade_amount, trade_count) VALUES ('%s', 'TAIEX', '%s', '%s', '%s', '%s', '%s', '%s', '%s');",
 		date, o, h, l, c, volume, amount, count)
 	//fmt.Println(sqlString)
 	_, err = db.Exec(sqlString)
@@ -480,7 +542,10 @@ func writeIndexInvestor(db *sql.DB, date string, investor IndexInvestor) bool {
 		return false
 	}
 
-	sqlString = fmt.Sprintf("INSERT INTO index_investors VALUES ('%s', 'TAIEX', ", date)
+	sqlString = fmt.Sprintf("INSERT INTO index_investors (trade_date, index_code, "+
+		"dealer_self_buy, dealer_self_sell, dealer_self_diff, dealer_hedge_buy, dealer_hedge_sell, dealer_hedge_diff, "+
+		"trust_buy, trust_sell, trust_diff, foreign_buy, foreign_sell, foreign_diff, total_buy, total_sell, total_diff, "+
+		"foreign_self_buy, foreign_self_sell, foreign_self_diff) VALUES ('%s', 'TAIEX', ", date)
 	sqlString += fmt.Sprintf("'%s', ", investor.DealerSelf.Buy)
 	sqlString += fmt.Sprintf("'%s', ", investor.DealerSelf.Sell)
 	sqlString += fmt.Sprintf("'%s', ", investor.DealerSelf.Difference)
@@ -517,7 +582,10 @@ func writeIndexMarginShort(db *sql.DB, date string, data IndexMarginShort) bool
 		return false
 	}
 
-	sqlString = fmt.Sprintf("INSERT INTO index_margin_short VALUES ('%s', 'TAIEX', ", date)
+	sqlString = fmt.Sprintf("INSERT INTO index_margin_short (trade_date, index_code, "+
+		"margin_new, margin_redemption, margin_outstanding, margin_last_remain, margin_remain, "+
+		"short_redemption, short_new, short_outstanding, short_last_remain, short_remain, "+
+		"margin_new_value, margin_redemption_value, margin_outstanding_value, margin_last_remain_value, margin_remain_value) VALUES ('%s', 'TAIEX', ", date)
 	sqlString += fmt.Sprintf("'%s', ", data.Margin.TodayNew)
 	sqlString += fmt.Sprintf("'%s', ", data.Margin.Redemption)
 	sqlString += fmt.Sprintf("'%s', ", data.Margin.Outstanding)
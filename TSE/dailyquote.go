@@ -94,6 +94,7 @@ func main() {
 	}
 	beginDate = time.Date(fromDate/10000, time.Month(fromDate%10000/100), fromDate%100, 0, 0, 0, 0, local)
 	log.Println("beginDate = ", beginDate)
+	written := false
 	for quoteDate.After(beginDate) {
 		log.Println(quoteDate)
 		quotes, ok := fetchDailyQuotes(quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day())
@@ -101,7 +102,7 @@ func main() {
 		if ok && ok2 {
 			//printDailyQuotes(quotes)
 			//printDailySubTrades(subTrades)
-			writeDailyQuotes(db, quotes)
+			written = writeDailyQuotes(db, quotes) || written
 			writeDailyIndices(db, quotes, subTrades)
 			if *flagLastTradeDay {
 				break
@@ -109,6 +110,21 @@ func main() {
 		}
 		quoteDate = quoteDate.AddDate(0, 0, -1)
 	}
+
+	if written {
+		refreshAdjustedQuotes(db)
+	}
+}
+
+// refreshAdjustedQuotes recomputes adjusted_quotes of migration 0005 with
+// the new quotes, concurrently so readers are not blocked.
+func refreshAdjustedQuotes(db *sql.DB) bool {
+	_, err := db.Exec("REFRESH MATERIALIZED VIEW CONCURRENTLY adjusted_quotes;")
+	if err != nil {
+		log.Println("refreshAdjustedQuotes", err)
+		return false
+	}
+	return true
 }
 
 func writeDailyQuotes(db *sql.DB, quotes *DailyQuote) bool {
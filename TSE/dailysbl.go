@@ -0,0 +1,324 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+const urlTSEDailySBLShort = "http://www.twse.com.tw/exchangeReport/TWT93U?response=json&date=%4d%02d%02d"
+const urlTSEDailySBLLending = "http://www.twse.com.tw/exchangeReport/TWT72U?response=json&date=%4d%02d%02d&selectType=SLBNLB"
+const kMinSize = 1024
+const kMinDate = 20000000
+
+const (
+	DB_USER     = "stock"
+	DB_PASSWORD = "test"
+	DB_NAME     = "stock"
+	DB_HOST     = "data.example.com"
+)
+
+type DailySBLShort struct {
+	Date   string
+	Fields []string   `json:"fields"`
+	Data   [][]string `json:"data"`
+}
+
+var dailySBLShort DailySBLShort
+
+// DailySBLLending is the lending balance of the SBL system and the brokers
+// (TWT72U), every borrowed share, not only the ones sold short.
+type DailySBLLending struct {
+	Date   string
+	Fields []string   `json:"fields"`
+	Data   [][]string `json:"data"`
+}
+
+var dailySBLLending DailySBLLending
+
+var flagFromDate = flag.Int("f", 0, "from date YYYYMMDD (default: the day after latest trade date in DB)")
+var flagToDate = flag.Int("t", 0, "to date YYYYMMDD (default: today)")
+var flagLastTradeDay = flag.Bool("l", false, "last trade day only")
+
+func main() {
+	flag.Parse()
+	dbinfo := fmt.Sprintf("user=%s password=%s dbname=%s host=%s sslmode=disable", DB_USER, DB_PASSWORD, DB_NAME, DB_HOST)
+	db, err := sql.Open("postgres", dbinfo)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	today := time.Now()
+	fromDate := *flagFromDate
+	toDate := *flagToDate
+	if fromDate == 0 || fromDate < kMinDate {
+		fromDate, err = getLastTradeDate(db)
+		if err != nil {
+			fromDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+		}
+	}
+	if toDate == 0 || toDate < kMinDate {
+		toDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+	}
+
+	log.Println(fromDate, toDate)
+
+	local, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		panic(err)
+	}
+
+	var quoteDate, beginDate time.Time
+	if *flagLastTradeDay {
+		quoteDate = today
+	} else {
+		quoteDate = time.Date(toDate/10000, time.Month(toDate%10000/100), toDate%100, 1, 0, 0, 0, local)
+	}
+	beginDate = time.Date(fromDate/10000, time.Month(fromDate%10000/100), fromDate%100, 0, 0, 0, 0, local)
+	log.Println("beginDate = ", beginDate)
+	for quoteDate.After(beginDate) {
+		log.Println(quoteDate)
+		time.Sleep(1 * time.Second)
+		quotes, ok := getDailySBLShort(quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day())
+		if ok {
+			//printDailySBLShort(quotes)
+			writeDailySBLShort(db, quotes)
+		}
+		time.Sleep(1 * time.Second)
+		lending, ok2 := getDailySBLLending(quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day())
+		if ok2 {
+			writeDailySBLLending(db, lending)
+		}
+		if (ok || ok2) && *flagLastTradeDay {
+			break
+		}
+		quoteDate = quoteDate.AddDate(0, 0, -1)
+	}
+}
+
+func writeDailySBLShort(db *sql.DB, quotes *DailySBLShort) bool {
+	//	var lastInsertId string
+
+	sqlString := "DELETE FROM daily_sbl_short WHERE trade_date='" + quotes.Date + "';"
+	//err := db.QueryRow(sqlString).Scan(&lastInsertId)
+	result, err := db.Exec(sqlString)
+	log.Println(result)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("DELETE error = %v\n", err)
+		return false
+	}
+
+	// "fields":["股票代號","股票名稱","前日餘額","賣出","買進","現券","今日餘額","次一營業日限額","前日餘額","當日賣出","當日還券","當日調整","當日餘額","次一營業日可限額","備註"]
+	// the first group is margin short sale (already in daily_margin_short), the second is SBL short sale 借券賣出, in shares
+	sqlString = "INSERT INTO daily_sbl_short (trade_date, security_code, sbl_last_remain, sbl_sell, sbl_return, sbl_adjust, sbl_remain, sbl_limit) VALUES\n"
+	for _, quote := range quotes.Data {
+		sqlString += fmt.Sprintf("('%s',", quotes.Date)
+		for i := 0; i < len(quote); i++ {
+			if i >= 1 && i <= 7 || i == 14 {
+				continue
+			}
+			if strings.Contains(quote[i], "--") || len(quote[i]) == 0 {
+				sqlString += " null"
+			} else {
+				sqlString += " '" + strings.Replace(quote[i], ",", "", -1) + "'"
+			}
+			if i != 13 {
+				sqlString += ","
+			}
+		}
+		sqlString += "),\n"
+	}
+	sqlString = strings.TrimRight(sqlString, ",\n") + ";"
+	//fmt.Println(sqlString)
+	result, err = db.Exec(sqlString)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// sblLendingColumns are the columns of daily_sbl_lending by the field names
+// of TWT72U, which have been renamed over the years.
+var sblLendingColumns = []struct {
+	column string
+	fields []string
+}{
+	{"lending_last_remain", []string{"前日餘額", "前日借券餘額"}},
+	{"lending", []string{"本日借券", "當日借券"}},
+	{"lending_return", []string{"本日還券", "當日還券"}},
+	{"lending_adjust", []string{"本日調整", "當日調整"}},
+	{"lending_remain", []string{"本日餘額", "當日餘額", "本日借券餘額"}},
+}
+
+func writeDailySBLLending(db *sql.DB, lending *DailySBLLending) bool {
+	// the first field is the security code, a missing field is written as null
+	index := make([]int, len(sblLendingColumns))
+	for i, c := range sblLendingColumns {
+		index[i] = -1
+		for j, field := range lending.Fields {
+			for _, name := range c.fields {
+				if strings.Contains(field, name) {
+					index[i] = j
+				}
+			}
+		}
+	}
+
+	sqlString := "DELETE FROM daily_sbl_lending WHERE trade_date='" + lending.Date + "';"
+	_, err := db.Exec(sqlString)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("DELETE error = %v\n", err)
+		return false
+	}
+
+	count := 0
+	sqlString = "INSERT INTO daily_sbl_lending (trade_date, security_code, lending_last_remain, lending, lending_return, lending_adjust, lending_remain) VALUES\n"
+	for _, record := range lending.Data {
+		if len(record) == 0 || len(strings.TrimSpace(record[0])) == 0 {
+			continue
+		}
+		sqlString += fmt.Sprintf("('%s', '%s'", lending.Date, strings.TrimSpace(record[0]))
+		for _, i := range index {
+			if i < 0 || i >= len(record) || strings.Contains(record[i], "--") || len(strings.TrimSpace(record[i])) == 0 {
+				sqlString += ", null"
+			} else {
+				sqlString += ", '" + strings.Replace(strings.TrimSpace(record[i]), ",", "", -1) + "'"
+			}
+		}
+		sqlString += "),\n"
+		count++
+	}
+	if count == 0 {
+		return true
+	}
+	sqlString = strings.TrimRight(sqlString, ",\n") + ";"
+	//fmt.Println(sqlString)
+	_, err = db.Exec(sqlString)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+func printDailySBLShort(quotes *DailySBLShort) {
+	log.Println(quotes.Date)
+	for _, quote := range quotes.Data {
+		for _, field := range quote {
+			fmt.Print(strings.Replace(field, ",", "", -1))
+			fmt.Print("\t")
+		}
+		fmt.Println()
+	}
+}
+
+func getLastTradeDate(db *sql.DB) (int, error) {
+	var row1 string
+	sqlString := "SELECT to_char(MAX(trade_date)+interval '1 day', 'YYYYMMDD') FROM daily_sbl_short"
+	err := db.QueryRow(sqlString).Scan(&row1)
+	if err != nil {
+		return 0, err
+	}
+	log.Println("the day after latest trade day = ", row1)
+
+	return strconv.Atoi(row1)
+}
+
+func getDailySBLShort(year int, month int, day int) (*DailySBLShort, bool) {
+	var url string
+	var contents []byte
+
+	url = fmt.Sprintf(urlTSEDailySBLShort, year, month, day)
+	log.Println(url)
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+
+	contents, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+
+	log.Println("----")
+	log.Println(resp.StatusCode)
+	log.Println(resp.Status)
+	log.Println("Body len = ", len(contents))
+
+	if len(contents) < kMinSize {
+		return nil, false
+	}
+
+	err = json.Unmarshal(contents, &dailySBLShort)
+	if err != nil {
+		log.Println("json unmarshal: ", err)
+		return nil, false
+	}
+
+	return &dailySBLShort, true
+}
+
+func getDailySBLLending(year int, month int, day int) (*DailySBLLending, bool) {
+	url := fmt.Sprintf(urlTSEDailySBLLending, year, month, day)
+	log.Println(url)
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+
+	log.Println("----")
+	log.Println(resp.StatusCode)
+	log.Println(resp.Status)
+	log.Println("Body len = ", len(contents))
+
+	if len(contents) < kMinSize {
+		return nil, false
+	}
+
+	dailySBLLending = DailySBLLending{}
+	err = json.Unmarshal(contents, &dailySBLLending)
+	if err != nil {
+		log.Println("json unmarshal: ", err)
+		return nil, false
+	}
+	if len(dailySBLLending.Data) == 0 {
+		return nil, false
+	}
+
+	return &dailySBLLending, true
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+// Only the latest trading day is published, run it after market close.
+// There is no history to backfill, a missed day stays missing.  all_etf.txt
+// lists ETFs only, ETN indicative values are not fetched.
+const urlTSEETFNav = "http://mis.twse.com.tw/stock/data/all_etf.txt"
+const kMinSize = 1024
+
+const (
+	DB_USER     = "stock"
+	DB_PASSWORD = "test"
+	DB_NAME     = "stock"
+	DB_HOST     = "data.example.com"
+)
+
+// a:代號 b:名稱 c:已發行受益權單位數 d:與前日已發行單位差異數 e:成交價 f:投資組合預估淨值
+// g:預估折溢價幅度 h:前一營業日單位淨值 i:資料日期 j:資料時間
+type ETFNav struct {
+	Code        string `json:"a"`
+	Name        string `json:"b"`
+	Units       string `json:"c"`
+	UnitsChange string `json:"d"`
+	Price       string `json:"e"`
+	EstimateNav string `json:"f"`
+	Premium     string `json:"g"`
+	LastNav     string `json:"h"`
+	Date        string `json:"i"`
+	Time        string `json:"j"`
+}
+
+type ETFNavList struct {
+	Groups []struct {
+		Data []ETFNav `json:"msgArray"`
+	} `json:"a1"`
+}
+
+func main() {
+	dbinfo := fmt.Sprintf("user=%s password=%s dbname=%s host=%s sslmode=disable", DB_USER, DB_PASSWORD, DB_NAME, DB_HOST)
+	db, err := sql.Open("postgres", dbinfo)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	navs, ok := fetchETFNavs()
+	if !ok || len(navs) == 0 {
+		return
+	}
+	//printETFNavs(navs)
+	if writeETFNavs(db, navs) {
+		updateLastNavs(db, navs)
+		updatePremiums(db)
+	}
+}
+
+func fetchETFNavs() ([]ETFNav, bool) {
+	var contents []byte
+	var navList ETFNavList
+
+	log.Println(urlTSEETFNav)
+	resp, err := http.Get(urlTSEETFNav)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+
+	contents, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+
+	log.Println("----")
+	log.Println(resp.StatusCode)
+	log.Println(resp.Status)
+	log.Println("Body len = ", len(contents))
+
+	if len(contents) < kMinSize {
+		return nil, false
+	}
+
+	err = json.Unmarshal(contents, &navList)
+	if err != nil {
+		log.Println("json unmarshal: ", err)
+		return nil, false
+	}
+
+	var navs []ETFNav
+	for _, group := range navList.Groups {
+		navs = append(navs, group.Data...)
+	}
+	return navs, true
+}
+
+func printETFNavs(navs []ETFNav) {
+	for _, nav := range navs {
+		fmt.Println(nav.Date, nav.Code, nav.Name, nav.Units, nav.UnitsChange, nav.Price, nav.EstimateNav, nav.Premium, nav.LastNav)
+	}
+}
+
+func sqlValue(s string) string {
+	s = strings.Replace(strings.TrimSpace(s), ",", "", -1)
+	if strings.Contains(s, "--") || len(s) == 0 {
+		return "null"
+	}
+	return "'" + s + "'"
+}
+
+func writeETFNavs(db *sql.DB, navs []ETFNav) bool {
+	tradeDate := navs[0].Date
+	sqlString := "DELETE FROM etf_nav WHERE trade_date='" + tradeDate + "';"
+	_, err := db.Exec(sqlString)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("DELETE error = %v\n", err)
+		return false
+	}
+
+	sqlString = "INSERT INTO etf_nav (trade_date, security_code, units_outstanding, units_change, estimated_nav) VALUES\n"
+	for _, nav := range navs {
+		if nav.Date != tradeDate {
+			continue
+		}
+		sqlString += fmt.Sprintf("('%s', '%s', %s, %s, %s),\n", nav.Date, nav.Code, sqlValue(nav.Units), sqlValue(nav.UnitsChange), sqlValue(nav.EstimateNav))
+	}
+	sqlString = strings.TrimRight(sqlString, ",\n") + ";"
+	//fmt.Println(sqlString)
+	_, err = db.Exec(sqlString)
+	if err != nil {
+		log.Println("writeETFNavs", err)
+		return false
+	}
+	return true
+}
+
+// updateLastNavs stores the official NAV published today for the previous
+// trade date, the last one of daily_quotes.  etf_nav may miss that date
+// after a missed run, then nothing is updated.
+func updateLastNavs(db *sql.DB, navs []ETFNav) bool {
+	var lastDate sql.NullString
+	err := db.QueryRow("SELECT to_char(MAX(trade_date), 'YYYY-MM-DD') FROM daily_quotes WHERE trade_date < $1", navs[0].Date).Scan(&lastDate)
+	if err != nil {
+		log.Println("updateLastNavs", err)
+		return false
+	}
+	if !lastDate.Valid {
+		return true
+	}
+
+	sqlString := ""
+	for _, nav := range navs {
+		value := sqlValue(nav.LastNav)
+		if value == "null" {
+			continue
+		}
+		sqlString += fmt.Sprintf("UPDATE etf_nav SET nav = %s WHERE security_code = '%s' AND trade_date = '%s';\n",
+			value, nav.Code, lastDate.String)
+	}
+	if len(sqlString) == 0 {
+		return true
+	}
+	_, err = db.Exec(sqlString)
+	if err != nil {
+		log.Println("updateLastNavs", err)
+		return false
+	}
+	return true
+}
+
+// updatePremiums computes premium/discount (%) of close price against the
+// official NAV, or the estimated NAV until the official one is published.
+func updatePremiums(db *sql.DB) bool {
+	sqlString := `UPDATE etf_nav e SET close_price = q.close_price,
+	premium_discount = round((q.close_price - COALESCE(e.nav, e.estimated_nav)) / COALESCE(e.nav, e.estimated_nav) * 100, 2)
+	FROM daily_quotes q
+	WHERE q.trade_date = e.trade_date AND q.security_code = e.security_code
+	AND COALESCE(e.nav, e.estimated_nav) > 0
+	AND (e.premium_discount IS NULL OR e.trade_date > current_date - 10);`
+	_, err := db.Exec(sqlString)
+	if err != nil {
+		log.Println("updatePremiums", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+const urlTSEForeignHolding = "http://www.twse.com.tw/fund/MI_QFIIS?response=json&date=%4d%02d%02d&selectType=ALLBUT0999"
+const kMinSize = 1024
+const kMinDate = 20000000
+
+const (
+	DB_USER     = "stock"
+	DB_PASSWORD = "test"
+	DB_NAME     = "stock"
+	DB_HOST     = "data.example.com"
+)
+
+type ForeignHolding struct {
+	Date   string
+	Fields []string   `json:"fields"`
+	Data   [][]string `json:"data"`
+}
+
+var foreignHolding ForeignHolding
+
+var flagFromDate = flag.Int("f", 0, "from date YYYYMMDD (default: the day after latest trade date in DB)")
+var flagToDate = flag.Int("t", 0, "to date YYYYMMDD (default: today)")
+var flagLastTradeDay = flag.Bool("l", false, "last trade day only")
+
+func main() {
+	flag.Parse()
+	dbinfo := fmt.Sprintf("user=%s password=%s dbname=%s host=%s sslmode=disable", DB_USER, DB_PASSWORD, DB_NAME, DB_HOST)
+	db, err := sql.Open("postgres", dbinfo)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	today := time.Now()
+	fromDate := *flagFromDate
+	toDate := *flagToDate
+	if fromDate == 0 || fromDate < kMinDate {
+		fromDate, err = getLastTradeDate(db)
+		if err != nil {
+			fromDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+		}
+	}
+	if toDate == 0 || toDate < kMinDate {
+		toDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+	}
+
+	log.Println(fromDate, toDate)
+
+	local, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		panic(err)
+	}
+
+	var quoteDate, beginDate time.Time
+	if *flagLastTradeDay {
+		quoteDate = today
+	} else {
+		quoteDate = time.Date(toDate/10000, time.Month(toDate%10000/100), toDate%100, 1, 0, 0, 0, local)
+	}
+	beginDate = time.Date(fromDate/10000, time.Month(fromDate%10000/100), fromDate%100, 0, 0, 0, 0, local)
+	log.Println("beginDate = ", beginDate)
+	for quoteDate.After(beginDate) {
+		log.Println(quoteDate)
+		time.Sleep(1 * time.Second)
+		quotes, ok := getForeignHoldings(quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day())
+		if ok {
+			//printForeignHoldings(quotes)
+			writeForeignHoldings(db, quotes)
+			if *flagLastTradeDay {
+				break
+			}
+		}
+		quoteDate = quoteDate.AddDate(0, 0, -1)
+	}
+}
+
+func writeForeignHoldings(db *sql.DB, quotes *ForeignHolding) bool {
+	//	var lastInsertId string
+
+	sqlString := "DELETE FROM foreign_holdings WHERE trade_date='" + quotes.Date + "';"
+	//err := db.QueryRow(sqlString).Scan(&lastInsertId)
+	result, err := db.Exec(sqlString)
+	log.Println(result)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("DELETE error = %v\n", err)
+		return false
+	}
+
+	// "fields":["證券代號","證券名稱","國際證券編碼","發行股數","外資及陸資尚可投資股數","全體外資及陸資持有股數","外資及陸資尚可投資比率","全體外資及陸資持股比率","外資及陸資共用法令投資上限比率","陸資法令投資上限比率","與前日異動原因","最近一次上市公司申報外資持股異動日期"]
+	sqlString = "INSERT INTO foreign_holdings (trade_date, security_code, issued_shares, available_shares, holding_shares, available_ratio, holding_ratio, upper_limit_ratio, china_upper_limit_ratio) VALUES\n"
+	for _, quote := range quotes.Data {
+		sqlString += fmt.Sprintf("('%s',", quotes.Date)
+		for i := 0; i < len(quote) && i <= 9; i++ {
+			if i == 1 || i == 2 {
+				continue
+			}
+			if strings.Contains(quote[i], "--") || len(quote[i]) == 0 {
+				sqlString += " null"
+			} else {
+				sqlString += " '" + strings.Replace(quote[i], ",", "", -1) + "'"
+			}
+			if i != 9 {
+				sqlString += ","
+			}
+		}
+		sqlString += "),\n"
+	}
+	sqlString = strings.TrimRight(sqlString, ",\n") + ";"
+	//fmt.Println(sqlString)
+	result, err = db.Exec(sqlString)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+func printForeignHoldings(quotes *ForeignHolding) {
+	log.Println(quotes.Date)
+	for _, quote := range quotes.Data {
+		for _, field := range quote {
+			fmt.Print(strings.Replace(field, ",", "", -1))
+			fmt.Print("\t")
+		}
+		fmt.Println()
+	}
+}
+
+func getLastTradeDate(db *sql.DB) (int, error) {
+	var row1 string
+	sqlString := "SELECT to_char(MAX(trade_date)+interval '1 day', 'YYYYMMDD') FROM foreign_holdings"
+	err := db.QueryRow(sqlString).Scan(&row1)
+	if err != nil {
+		return 0, err
+	}
+	log.Println("the day after latest trade day = ", row1)
+
+	return strconv.Atoi(row1)
+}
+
+func getForeignHoldings(year int, month int, day int) (*ForeignHolding, bool) {
+	var url string
+	var contents []byte
+
+	url = fmt.Sprintf(urlTSEForeignHolding, year, month, day)
+	log.Println(url)
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+
+	contents, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+
+	log.Println("----")
+	log.Println(resp.StatusCode)
+	log.Println(resp.Status)
+	log.Println("Body len = ", len(contents))
+
+	if len(contents) < kMinSize {
+		return nil, false
+	}
+
+	err = json.Unmarshal(contents, &foreignHolding)
+	if err != nil {
+		log.Println("json unmarshal: ", err)
+		return nil, false
+	}
+
+	return &foreignHolding, true
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+const (
+	urlTSEBlockTrade      = "http://www.twse.com.tw/block/BFIAUU?response=json&date=%4d%02d%02d&selectType=S"
+	urlTSEOddLotTrade     = "http://www.twse.com.tw/exchangeReport/TWT53U?response=json&date=%4d%02d%02d&selectType=ALLBUT0999"
+	urlTSEFixedPriceTrade = "http://www.twse.com.tw/exchangeReport/BFT41U?response=json&date=%4d%02d%02d&selectType=ALLBUT0999"
+	kMinDate              = 20000000
+)
+
+const (
+	DB_USER     = "stock"
+	DB_PASSWORD = "test"
+	DB_NAME     = "stock"
+	DB_HOST     = "data.example.com"
+)
+
+type jsonContent struct {
+	Status string `json:"stat"`
+	Date   string
+	Data   [][]string `json:"data"`
+}
+
+type SessionTrade struct {
+	Code   string
+	Volume int64
+	Count  int64
+	Amount int64
+	Price  string
+}
+
+type Session struct {
+	Name string // block / odd_lot / fixed_price
+	URL  string
+	// column index of code, volume, count, amount and price, -1 if missing
+	Columns [5]int
+}
+
+// BFIAUU "fields":["證券代號","證券名稱","交易別","成交價","成交股數","成交金額"], one row per trade
+// TWT53U "fields":["證券代號","證券名稱","成交股數","成交筆數","成交金額","成交價","最後揭示買價","最後揭示買量","最後揭示賣價","最後揭示賣量"]
+// BFT41U "fields":["證券代號","證券名稱","成交股數","成交筆數","成交金額","成交價","最後揭示買量","最後揭示賣量"]
+var sessions = [...]Session{
+	{"block", urlTSEBlockTrade, [5]int{0, 4, -1, 5, 3}},
+	{"odd_lot", urlTSEOddLotTrade, [5]int{0, 2, 3, 4, 5}},
+	{"fixed_price", urlTSEFixedPriceTrade, [5]int{0, 2, 3, 4, 5}},
+}
+
+var flagFromDate = flag.Int("f", 0, "from date YYYYMMDD (default: the day after latest trade date in DB)")
+var flagToDate = flag.Int("t", 0, "to date YYYYMMDD (default: today)")
+var flagLastTradeDay = flag.Bool("l", false, "last trade day only")
+
+func main() {
+	flag.Parse()
+	dbinfo := fmt.Sprintf("user=%s password=%s dbname=%s host=%s sslmode=disable", DB_USER, DB_PASSWORD, DB_NAME, DB_HOST)
+	db, err := sql.Open("postgres", dbinfo)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	today := time.Now()
+	fromDate := *flagFromDate
+	toDate := *flagToDate
+	if fromDate == 0 || fromDate < kMinDate {
+		fromDate, err = fetchLastTradeDate(db)
+		if err != nil {
+			fromDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+		}
+	}
+	if toDate == 0 || toDate < kMinDate {
+		toDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+	}
+
+	log.Println(fromDate, toDate)
+
+	local, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		panic(err)
+	}
+
+	var quoteDate, beginDate time.Time
+	if *flagLastTradeDay {
+		quoteDate = today
+	} else {
+		quoteDate = time.Date(toDate/10000, time.Month(toDate%10000/100), toDate%100, 1, 0, 0, 0, local)
+	}
+	beginDate = time.Date(fromDate/10000, time.Month(fromDate%10000/100), fromDate%100, 0, 0, 0, 0, local)
+	log.Println("beginDate = ", beginDate)
+	for quoteDate.After(beginDate) {
+		log.Println(quoteDate)
+		dateString := fmt.Sprintf("%4d%02d%02d", quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day())
+		found := false
+		for _, session := range sessions {
+			time.Sleep(1 * time.Second)
+			trades, ok := fetchSessionTrades(session, quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day())
+			if !ok {
+				continue
+			}
+			found = true
+			//printSessionTrades(session, trades)
+			writeSessionTrades(db, dateString, session, trades)
+		}
+		if found && *flagLastTradeDay {
+			break
+		}
+		quoteDate = quoteDate.AddDate(0, 0, -1)
+	}
+}
+
+func fetchLastTradeDate(db *sql.DB) (int, error) {
+	var row1 string
+	sqlString := "SELECT to_char(MAX(trade_date)+interval '1 day', 'YYYYMMDD') FROM session_trades"
+	err := db.QueryRow(sqlString).Scan(&row1)
+	if err != nil {
+		return 0, err
+	}
+	log.Println("the day after latest trade day = ", row1)
+
+	return strconv.Atoi(row1)
+}
+
+func parseInt(s string) int64 {
+	n, _ := strconv.ParseInt(strings.Replace(strings.TrimSpace(s), ",", "", -1), 10, 64)
+	return n
+}
+
+// fetchSessionTrades returns trades summed per security.
+func fetchSessionTrades(session Session, year int, month int, day int) ([]SessionTrade, bool) {
+	var url string
+	var contents []byte
+	var jsonData jsonContent
+
+	url = fmt.Sprintf(session.URL, year, month, day)
+	log.Println(url)
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+
+	contents, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+
+	log.Println("----")
+	log.Println(resp.StatusCode)
+	log.Println(resp.Status)
+	log.Println("Body len = ", len(contents))
+
+	err = json.Unmarshal(contents, &jsonData)
+	if err != nil {
+		log.Println("json unmarshal: ", err)
+		return nil, false
+	}
+
+	if jsonData.Status != "OK" {
+		log.Println("stat: ", jsonData.Status)
+		return nil, false
+	}
+
+	var codes []string
+	trades := make(map[string]*SessionTrade)
+	for _, data := range jsonData.Data {
+		if len(data) <= session.Columns[4] {
+			continue
+		}
+		code := strings.TrimSpace(data[session.Columns[0]])
+		if len(code) == 0 || strings.Contains(code, "計") {
+			continue
+		}
+		trade, ok := trades[code]
+		if !ok {
+			trade = &SessionTrade{Code: code}
+			trades[code] = trade
+			codes = append(codes, code)
+		}
+		trade.Volume += parseInt(data[session.Columns[1]])
+		if session.Columns[2] < 0 {
+			trade.Count++
+		} else {
+			trade.Count += parseInt(data[session.Columns[2]])
+		}
+		trade.Amount += parseInt(data[session.Columns[3]])
+		price := strings.Replace(strings.TrimSpace(data[session.Columns[4]]), ",", "", -1)
+		if !strings.Contains(price, "--") && len(price) > 0 {
+			trade.Price = price
+		}
+	}
+
+	var out []SessionTrade
+	for _, code := range codes {
+		out = append(out, *trades[code])
+	}
+	return out, true
+}
+
+func printSessionTrades(session Session, trades []SessionTrade) {
+	log.Println(session.Name)
+	for _, trade := range trades {
+		fmt.Println(trade.Code, trade.Volume, trade.Count, trade.Amount, trade.Price)
+	}
+}
+
+func writeSessionTrades(db *sql.DB, date string, session Session, trades []SessionTrade) bool {
+	sqlString := "DELETE FROM session_trades WHERE trade_date='" + date + "' AND trade_session='" + session.Name + "';"
+	_, err := db.Exec(sqlString)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("DELETE error = %v\n", err)
+		return false
+	}
+
+	if len(trades) == 0 {
+		return true
+	}
+
+	sqlString = "INSERT INTO session_trades (trade_date, security_code, trade_session, trade_volume, trade_count, trade_amount, trade_price) VALUES\n"
+	for _, trade := range trades {
+		price := "null"
+		if len(trade.Price) > 0 {
+			price = "'" + trade.Price + "'"
+		}
+		sqlString += fmt.Sprintf("('%s', '%s', '%s', '%d', '%d', '%d', %s),\n", date, trade.Code, session.Name, trade.Volume, trade.Count, trade.Amount, price)
+	}
+	sqlString = strings.TrimRight(sqlString, ",\n") + ";"
+	//fmt.Println(sqlString)
+	_, err = db.Exec(sqlString)
+	if err != nil {
+		log.Println("writeSessionTrades", err)
+		return false
+	}
+	return true
+}
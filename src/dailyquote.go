@@ -91,6 +91,7 @@ func main() {
 	}
 	beginDate = time.Date(fromDate/10000, time.Month(fromDate%10000/100), fromDate%100, 0, 0, 0, 0, local)
 	log.Println("beginDate = ", beginDate)
+	written := false
 	for quoteDate.After(beginDate) {
 		log.Println(quoteDate)
 		quotes, ok := fetchTSEDailyQuotes(quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day())
@@ -103,6 +104,8 @@ func main() {
 				writeTSEDailyQuotes(db, quotes)
 				//printOTCDailyQuotes(quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day(), csvString)
 				writeOTCDailyQuotes(db, quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day(), csvString)
+				writeOTCSharesOutstanding(db, quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day(), csvString)
+				written = true
 			} else {
 				log.Printf("DELETE error = %v\n", err)
 			}
@@ -112,6 +115,21 @@ func main() {
 		}
 		quoteDate = quoteDate.AddDate(0, 0, -1)
 	}
+
+	if written {
+		refreshAdjustedQuotes(db)
+	}
+}
+
+// refreshAdjustedQuotes recomputes adjusted_quotes of migration 0005 with
+// the new quotes, concurrently so readers are not blocked.
+func refreshAdjustedQuotes(db *sql.DB) bool {
+	_, err := db.Exec("REFRESH MATERIALIZED VIEW CONCURRENTLY adjusted_quotes;")
+	if err != nil {
+		log.Println("refreshAdjustedQuotes", err)
+		return false
+	}
+	return true
 }
 
 func writeTSEDailyQuotes(db *sql.DB, quotes *DailyQuote) bool {
@@ -318,3 +336,44 @@ func writeOTCDailyQuotes(db *sql.DB, year, month, day int, csvString string) boo
 	}
 	return true
 }
+
+// writeOTCSharesOutstanding keeps the 發行股數 column of the OTC quotes,
+// TSE shares come from MOPS/sharesoutstanding.go.
+func writeOTCSharesOutstanding(db *sql.DB, year, month, day int, csvString string) bool {
+	csvr := csv.NewReader(strings.NewReader(csvString))
+
+	records, err := csvr.ReadAll()
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+
+	quoteDate := fmt.Sprintf("%04d/%02d/%02d", year, month, day)
+
+	sqlString := "INSERT INTO shares_outstanding (trade_date, security_code, shares) VALUES\n"
+	count := 0
+	for _, record := range records {
+		if len(record) < 13 {
+			continue
+		}
+		shares := strings.Replace(strings.TrimSpace(record[12]), ",", "", -1)
+		if strings.Contains(shares, "--") || len(shares) == 0 {
+			continue
+		}
+		sqlString += fmt.Sprintf("('%s', '%s', '%s'),\n", quoteDate, strings.TrimSpace(record[0]), shares)
+		count++
+	}
+	if count == 0 {
+		return true
+	}
+
+	sqlString = strings.TrimRight(sqlString, ",\n")
+	sqlString += "\nON CONFLICT (trade_date, security_code) DO UPDATE SET shares = EXCLUDED.shares;"
+	//fmt.Println(sqlString)
+	_, err = db.Exec(sqlString)
+	if err != nil {
+		log.Println("writeOTCSharesOutstanding", err)
+		return false
+	}
+	return true
+}
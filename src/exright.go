@@ -0,0 +1,343 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+const urlTSEExRight = "http://www.twse.com.tw/exchangeReport/TWT49U?response=json&strDate=%4d%02d%02d&endDate=%4d%02d%02d"
+const urlOTCExRight = "http://www.tpex.org.tw/web/stock/exright/dailyquo/exDailyQ_result.php?l=zh-tw&d=%d/%02d/%02d&ed=%d/%02d/%02d"
+const kMinDate = 20000000
+
+const (
+	DB_USER     = "stock"
+	DB_PASSWORD = "test"
+	DB_NAME     = "stock"
+	DB_HOST     = "data.example.com"
+)
+
+type TSEExRight struct {
+	Status string     `json:"stat"`
+	Fields []string   `json:"fields"`
+	Data   [][]string `json:"data"`
+}
+
+type OTCExRight struct {
+	Data [][]interface{} `json:"aaData"`
+}
+
+type CorporateAction struct {
+	ExDate         string
+	SecurityCode   string
+	ActionType     string // 權 / 息 / 權息
+	BeforePrice    string
+	ReferencePrice string
+	RightsValue    string
+	CashDividend   string
+	StockDividend  string // shares per 1000 shares
+}
+
+var flagFromDate = flag.Int("f", 0, "from date YYYYMMDD (default: the day after latest ex-date in DB)")
+var flagToDate = flag.Int("t", 0, "to date YYYYMMDD (default: today)")
+
+func main() {
+	flag.Parse()
+	dbinfo := fmt.Sprintf("user=%s password=%s dbname=%s host=%s sslmode=disable", DB_USER, DB_PASSWORD, DB_NAME, DB_HOST)
+	db, err := sql.Open("postgres", dbinfo)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	today := time.Now()
+	fromDate := *flagFromDate
+	toDate := *flagToDate
+	if fromDate == 0 || fromDate < kMinDate {
+		fromDate, err = fetchLastExDate(db)
+		if err != nil {
+			fromDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+		}
+	}
+	if toDate == 0 || toDate < kMinDate {
+		toDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+	}
+
+	log.Println(fromDate, toDate)
+
+	local, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		panic(err)
+	}
+
+	// both reports accept a date range, query one month at a time
+	beginDate := time.Date(fromDate/10000, time.Month(fromDate%10000/100), fromDate%100, 0, 0, 0, 0, local)
+	endDate := time.Date(toDate/10000, time.Month(toDate%10000/100), toDate%100, 0, 0, 0, 0, local)
+	written := false
+	for startDate := beginDate; !startDate.After(endDate); {
+		stopDate := time.Date(startDate.Year(), startDate.Month()+1, 0, 0, 0, 0, 0, local)
+		if stopDate.After(endDate) {
+			stopDate = endDate
+		}
+		log.Println(startDate, stopDate)
+		time.Sleep(1 * time.Second)
+		tseActions, ok := fetchTSEExRights(startDate, stopDate)
+		otcActions, ok2 := fetchOTCExRights(startDate, stopDate)
+		if ok || ok2 {
+			actions := append(tseActions, otcActions...)
+			//printCorporateActions(actions)
+			// the month is replaced only when both markets answered, else
+			// the rows fetched are upserted and the others kept
+			if writeCorporateActions(db, startDate, stopDate, actions, ok && ok2) {
+				written = true
+			}
+		}
+		startDate = stopDate.AddDate(0, 0, 1)
+	}
+
+	if written {
+		refreshAdjustedQuotes(db)
+	}
+}
+
+func fetchLastExDate(db *sql.DB) (int, error) {
+	var row1 string
+	sqlString := "SELECT to_char(MAX(ex_date)+interval '1 day', 'YYYYMMDD') FROM corporate_actions"
+	err := db.QueryRow(sqlString).Scan(&row1)
+	if err != nil {
+		return 0, err
+	}
+	log.Println("the day after latest ex-date = ", row1)
+
+	return strconv.Atoi(row1)
+}
+
+func httpGet(url string) ([]byte, bool) {
+	log.Println(url)
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+
+	log.Println("----")
+	log.Println(resp.StatusCode)
+	log.Println(resp.Status)
+	log.Println("Body len = ", len(contents))
+
+	return contents, true
+}
+
+// rocDate converts 107年06月29日 or 107/06/29 to 2018/06/29
+func rocDate(s string) string {
+	var year, month, day int
+	s = strings.NewReplacer("年", "/", "月", "/", "日", "").Replace(strings.TrimSpace(s))
+	if n, _ := fmt.Sscanf(s, "%d/%d/%d", &year, &month, &day); n != 3 {
+		return ""
+	}
+	return fmt.Sprintf("%04d/%02d/%02d", year+1911, month, day)
+}
+
+func number(s string) string {
+	s = strings.Replace(strings.TrimSpace(s), ",", "", -1)
+	if strings.Contains(s, "--") || len(s) == 0 {
+		return ""
+	}
+	return s
+}
+
+func fetchTSEExRights(startDate, stopDate time.Time) ([]CorporateAction, bool) {
+	var exRight TSEExRight
+
+	url := fmt.Sprintf(urlTSEExRight, startDate.Year(), int(startDate.Month()), startDate.Day(), stopDate.Year(), int(stopDate.Month()), stopDate.Day())
+	contents, ok := httpGet(url)
+	if !ok {
+		return nil, false
+	}
+
+	err := json.Unmarshal(contents, &exRight)
+	if err != nil {
+		log.Println("json unmarshal: ", err)
+		return nil, false
+	}
+
+	if exRight.Status != "OK" {
+		// no ex-right in this period, or an error message, keep the
+		// stored rows either way
+		log.Println("stat: ", exRight.Status)
+		return nil, false
+	}
+
+	// "fields":["資料日期","股票代號","股票名稱","除權息前收盤價","除權息參考價","權值+息值","權/息","漲停價格","跌停價格","開盤競價基準","減除股利參考價","詳細資料",...]
+	var actions []CorporateAction
+	for _, data := range exRight.Data {
+		if len(data) < 7 {
+			continue
+		}
+		var action CorporateAction
+		action.ExDate = rocDate(data[0])
+		action.SecurityCode = strings.TrimSpace(data[1])
+		action.BeforePrice = number(data[3])
+		action.ReferencePrice = number(data[4])
+		action.RightsValue = number(data[5])
+		action.ActionType = strings.TrimSpace(data[6])
+		// TWT49U only reports the sum, split it when there is a single kind.
+		// 權息 is left null, the split is only in the detail page.
+		switch action.ActionType {
+		case "息":
+			action.CashDividend = action.RightsValue
+			action.StockDividend = "0"
+		case "權":
+			action.CashDividend = "0"
+			action.StockDividend = stockDividend(action.BeforePrice, action.ReferencePrice)
+		}
+		actions = append(actions, action)
+	}
+	return actions, true
+}
+
+// stockDividend returns the shares per 1000 shares of a stock dividend, the
+// reference price is before / (1 + shares / 1000).  For rights issues it is
+// the equivalent free shares.
+func stockDividend(beforePrice, referencePrice string) string {
+	before, err := strconv.ParseFloat(beforePrice, 64)
+	if err != nil {
+		return ""
+	}
+	reference, err := strconv.ParseFloat(referencePrice, 64)
+	if err != nil || reference <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat((before/reference-1)*1000, 'f', 2, 64)
+}
+
+func fetchOTCExRights(startDate, stopDate time.Time) ([]CorporateAction, bool) {
+	var exRight OTCExRight
+
+	url := fmt.Sprintf(urlOTCExRight, startDate.Year()-1911, int(startDate.Month()), startDate.Day(), stopDate.Year()-1911, int(stopDate.Month()), stopDate.Day())
+	contents, ok := httpGet(url)
+	if !ok {
+		return nil, false
+	}
+
+	err := json.Unmarshal(contents, &exRight)
+	if err != nil {
+		log.Println("json unmarshal: ", err)
+		return nil, false
+	}
+
+	// 除權息日期,代號,名稱,除權息前收盤價,除權息參考價,權值,息值,權值+息值,權/息,漲停價,跌停價,開始交易基準價,減除股利參考價,現金股利,每仟股無償配股,...
+	var actions []CorporateAction
+	for _, row := range exRight.Data {
+		if len(row) < 15 {
+			continue
+		}
+		data := make([]string, len(row))
+		for i, field := range row {
+			data[i] = fmt.Sprint(field)
+		}
+		var action CorporateAction
+		action.ExDate = rocDate(data[0])
+		action.SecurityCode = strings.TrimSpace(data[1])
+		action.BeforePrice = number(data[3])
+		action.ReferencePrice = number(data[4])
+		action.RightsValue = number(data[7])
+		action.ActionType = strings.TrimSpace(data[8])
+		action.CashDividend = number(data[13])
+		action.StockDividend = number(data[14])
+		actions = append(actions, action)
+	}
+	return actions, true
+}
+
+func printCorporateActions(actions []CorporateAction) {
+	for _, action := range actions {
+		fmt.Println(action.ExDate, action.SecurityCode, action.ActionType, action.BeforePrice, action.ReferencePrice, action.RightsValue, action.CashDividend, action.StockDividend)
+	}
+}
+
+func sqlValue(s string) string {
+	if len(s) == 0 {
+		return "null"
+	}
+	return "'" + s + "'"
+}
+
+func writeCorporateActions(db *sql.DB, startDate, stopDate time.Time, actions []CorporateAction, replace bool) bool {
+	if replace {
+		sqlString := fmt.Sprintf("DELETE FROM corporate_actions WHERE ex_date BETWEEN '%s' AND '%s';",
+			startDate.Format("2006/01/02"), stopDate.Format("2006/01/02"))
+		_, err := db.Exec(sqlString)
+		if err != nil && err != sql.ErrNoRows {
+			log.Printf("DELETE error = %v\n", err)
+			return false
+		}
+	}
+
+	if len(actions) == 0 {
+		return true
+	}
+
+	sqlString := "INSERT INTO corporate_actions (ex_date, security_code, action_type, before_price, reference_price, rights_value, cash_dividend, stock_dividend) VALUES\n"
+	for _, action := range actions {
+		if len(action.ExDate) == 0 {
+			continue
+		}
+		sqlString += fmt.Sprintf("('%s', '%s', '%s', %s, %s, %s, %s, %s),\n", action.ExDate, action.SecurityCode, action.ActionType,
+			sqlValue(action.BeforePrice), sqlValue(action.ReferencePrice), sqlValue(action.RightsValue),
+			sqlValue(action.CashDividend), sqlValue(action.StockDividend))
+	}
+	sqlString = strings.TrimRight(sqlString, ",\n")
+	sqlString += `
+ON CONFLICT (ex_date, security_code) DO UPDATE SET
+	action_type = EXCLUDED.action_type,
+	before_price = EXCLUDED.before_price,
+	reference_price = EXCLUDED.reference_price,
+	rights_value = EXCLUDED.rights_value,
+	cash_dividend = EXCLUDED.cash_dividend,
+	stock_dividend = EXCLUDED.stock_dividend;`
+	//fmt.Println(sqlString)
+	_, err := db.Exec(sqlString)
+	if err != nil {
+		log.Println("writeCorporateActions", err)
+		return false
+	}
+	return true
+}
+
+// refreshAdjustedQuotes recomputes adjusted_quotes with the new actions,
+// concurrently so readers are not blocked.  The quote crawlers refresh it
+// too.
+func refreshAdjustedQuotes(db *sql.DB) bool {
+	_, err := db.Exec("REFRESH MATERIALIZED VIEW CONCURRENTLY adjusted_quotes;")
+	if err != nil {
+		log.Println("refreshAdjustedQuotes", err)
+		return false
+	}
+	return true
+}
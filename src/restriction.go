@@ -0,0 +1,326 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+const (
+	urlTSEAttention   = "http://www.twse.com.tw/announcement/notice?response=json&startDate=%4d%02d%02d&endDate=%4d%02d%02d"
+	urlTSEDisposition = "http://www.twse.com.tw/announcement/punish?response=json&startDate=%4d%02d%02d&endDate=%4d%02d%02d"
+	urlTSESuspension  = "http://www.twse.com.tw/exchangeReport/TWTAWU?response=json&startDate=%4d%02d%02d&endDate=%4d%02d%02d&querytype=3"
+	urlOTCAttention   = "http://www.tpex.org.tw/web/bulletin/attention_information/trading_attention_information_result.php?l=zh-tw&sd=%d/%02d/%02d&ed=%d/%02d/%02d"
+	urlOTCDisposition = "http://www.tpex.org.tw/web/bulletin/disposal_information/disposal_information_result.php?l=zh-tw&sd=%d/%02d/%02d&ed=%d/%02d/%02d"
+	urlOTCSuspension  = "http://www.tpex.org.tw/web/stock/trading/trading_halt/halt_result.php?l=zh-tw&sd=%d/%02d/%02d&ed=%d/%02d/%02d"
+	kMinDate          = 20000000
+)
+
+const (
+	DB_USER     = "stock"
+	DB_PASSWORD = "test"
+	DB_NAME     = "stock"
+	DB_HOST     = "data.example.com"
+)
+
+type TSEList struct {
+	Status string     `json:"stat"`
+	Data   [][]string `json:"data"`
+}
+
+type OTCList struct {
+	Data [][]interface{} `json:"aaData"`
+}
+
+type Restriction struct {
+	SecurityCode string
+	Type         string
+	Market       string
+	StartDate    string
+	EndDate      string // empty if not ended yet
+	Reason       string
+}
+
+type RestrictionList struct {
+	Type   string // attention / disposition / suspension
+	Market string // tse / otc
+	URL    string
+	// column index of code, start date, end date and reason, -1 if missing.
+	// the same start and end column is a range like 107/06/29～107/07/12,
+	// no end column means the restriction lasts the start date only.
+	Columns [4]int
+}
+
+// TSE notice  "fields":["編號","證券代號","證券名稱","累計次數","注意交易資訊","日期","收盤價","本益比"]
+// TSE punish  "fields":["編號","公布日期","證券代號","證券名稱","累計","處置條件","處置起迄時間","處置措施","處置內容","備註"]
+// TSE TWTAWU  "fields":["證券代號","證券名稱","停止交易日期","恢復交易日期","停止交易原因"]
+// OTC 注意股票 編號,證券代號,證券名稱,累計次數,注意交易資訊,公告日期,收盤價,本益比
+// OTC 處置股票 編號,公布日期,證券代號,證券名稱,累計,處置起訖時間,處置條件,處置內容
+// OTC 停止買賣 證券代號,證券名稱,停止買賣日期,恢復買賣日期,停止買賣原因
+var lists = [...]RestrictionList{
+	{"attention", "tse", urlTSEAttention, [4]int{1, 5, -1, 4}},
+	{"disposition", "tse", urlTSEDisposition, [4]int{2, 6, 6, 5}},
+	{"suspension", "tse", urlTSESuspension, [4]int{0, 2, 3, 4}},
+	{"attention", "otc", urlOTCAttention, [4]int{1, 5, -1, 4}},
+	{"disposition", "otc", urlOTCDisposition, [4]int{2, 5, 5, 6}},
+	{"suspension", "otc", urlOTCSuspension, [4]int{0, 2, 3, 4}},
+}
+
+var flagFromDate = flag.Int("f", 0, "from date YYYYMMDD (default: the latest start date in DB)")
+var flagToDate = flag.Int("t", 0, "to date YYYYMMDD (default: today)")
+
+func main() {
+	flag.Parse()
+	dbinfo := fmt.Sprintf("user=%s password=%s dbname=%s host=%s sslmode=disable", DB_USER, DB_PASSWORD, DB_NAME, DB_HOST)
+	db, err := sql.Open("postgres", dbinfo)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	today := time.Now()
+	fromDate := *flagFromDate
+	toDate := *flagToDate
+	if fromDate == 0 || fromDate < kMinDate {
+		fromDate, err = fetchLastStartDate(db)
+		if err != nil {
+			fromDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+		}
+	}
+	if toDate == 0 || toDate < kMinDate {
+		toDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+	}
+
+	log.Println(fromDate, toDate)
+
+	local, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		panic(err)
+	}
+
+	// every list accepts a date range, query one month at a time
+	beginDate := time.Date(fromDate/10000, time.Month(fromDate%10000/100), fromDate%100, 0, 0, 0, 0, local)
+	endDate := time.Date(toDate/10000, time.Month(toDate%10000/100), toDate%100, 0, 0, 0, 0, local)
+	for startDate := beginDate; !startDate.After(endDate); {
+		stopDate := time.Date(startDate.Year(), startDate.Month()+1, 0, 0, 0, 0, 0, local)
+		if stopDate.After(endDate) {
+			stopDate = endDate
+		}
+		log.Println(startDate, stopDate)
+		for _, list := range lists {
+			time.Sleep(1 * time.Second)
+			restrictions, ok := fetchRestrictions(list, startDate, stopDate)
+			if !ok {
+				continue
+			}
+			//printRestrictions(restrictions)
+			writeRestrictions(db, restrictions)
+		}
+		startDate = stopDate.AddDate(0, 0, 1)
+	}
+}
+
+func fetchLastStartDate(db *sql.DB) (int, error) {
+	var row1 string
+	// announcements of the latest day may be incomplete, fetch it again
+	sqlString := "SELECT to_char(MAX(start_date), 'YYYYMMDD') FROM trading_restrictions WHERE start_date <= now()"
+	err := db.QueryRow(sqlString).Scan(&row1)
+	if err != nil {
+		return 0, err
+	}
+	log.Println("the latest start date = ", row1)
+
+	return strconv.Atoi(row1)
+}
+
+func httpGet(url string) ([]byte, bool) {
+	log.Println(url)
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+
+	log.Println("----")
+	log.Println(resp.StatusCode)
+	log.Println(resp.Status)
+	log.Println("Body len = ", len(contents))
+
+	return contents, true
+}
+
+// rocDate converts 107年06月29日, 107/06/29 or 1070629 to 2018/06/29
+func rocDate(s string) string {
+	var year, month, day int
+	s = strings.NewReplacer("年", "/", "月", "/", "日", "").Replace(strings.TrimSpace(s))
+	if n, _ := fmt.Sscanf(s, "%d/%d/%d", &year, &month, &day); n != 3 {
+		date, err := strconv.Atoi(s)
+		if err != nil || date < 100000 {
+			return ""
+		}
+		year, month, day = date/10000, date%10000/100, date%100
+	}
+	return fmt.Sprintf("%04d/%02d/%02d", year+1911, month, day)
+}
+
+// dateRange splits 107/06/29～107/07/12
+func dateRange(s string) (string, string) {
+	s = strings.NewReplacer("～", "~", "－", "~", "-", "~", "至", "~").Replace(s)
+	dates := strings.SplitN(s, "~", 2)
+	if len(dates) != 2 {
+		return rocDate(s), ""
+	}
+	return rocDate(dates[0]), rocDate(dates[1])
+}
+
+// dayBefore returns the last suspended day of a resume date
+func dayBefore(date string) string {
+	t, err := time.Parse("2006/01/02", date)
+	if err != nil {
+		return ""
+	}
+	return t.AddDate(0, 0, -1).Format("2006/01/02")
+}
+
+func fetchRestrictions(list RestrictionList, startDate, stopDate time.Time) ([]Restriction, bool) {
+	var rows [][]string
+
+	if list.Market == "tse" {
+		var tseList TSEList
+		url := fmt.Sprintf(list.URL, startDate.Year(), int(startDate.Month()), startDate.Day(), stopDate.Year(), int(stopDate.Month()), stopDate.Day())
+		contents, ok := httpGet(url)
+		if !ok {
+			return nil, false
+		}
+		err := json.Unmarshal(contents, &tseList)
+		if err != nil {
+			log.Println("json unmarshal: ", err)
+			return nil, false
+		}
+		if tseList.Status != "OK" {
+			// nothing in this period
+			log.Println("stat: ", tseList.Status)
+			return nil, true
+		}
+		rows = tseList.Data
+	} else {
+		var otcList OTCList
+		url := fmt.Sprintf(list.URL, startDate.Year()-1911, int(startDate.Month()), startDate.Day(), stopDate.Year()-1911, int(stopDate.Month()), stopDate.Day())
+		contents, ok := httpGet(url)
+		if !ok {
+			return nil, false
+		}
+		err := json.Unmarshal(contents, &otcList)
+		if err != nil {
+			log.Println("json unmarshal: ", err)
+			return nil, false
+		}
+		for _, row := range otcList.Data {
+			data := make([]string, len(row))
+			for i, field := range row {
+				data[i] = fmt.Sprint(field)
+			}
+			rows = append(rows, data)
+		}
+	}
+
+	var restrictions []Restriction
+	for _, data := range rows {
+		if len(data) <= list.Columns[0] || len(data) <= list.Columns[1] || len(data) <= list.Columns[2] || len(data) <= list.Columns[3] {
+			continue
+		}
+		r := Restriction{Type: list.Type, Market: list.Market}
+		r.SecurityCode = strings.TrimSpace(data[list.Columns[0]])
+		switch {
+		case list.Columns[2] < 0:
+			r.StartDate = rocDate(data[list.Columns[1]])
+			r.EndDate = r.StartDate
+		case list.Columns[2] == list.Columns[1]:
+			r.StartDate, r.EndDate = dateRange(data[list.Columns[1]])
+		default:
+			r.StartDate = rocDate(data[list.Columns[1]])
+			// suspended until the day before resuming
+			r.EndDate = dayBefore(rocDate(data[list.Columns[2]]))
+		}
+		if list.Columns[3] >= 0 {
+			r.Reason = strings.TrimSpace(data[list.Columns[3]])
+		}
+		if len(r.SecurityCode) == 0 || len(r.StartDate) == 0 {
+			continue
+		}
+		restrictions = append(restrictions, r)
+	}
+	return restrictions, true
+}
+
+func printRestrictions(restrictions []Restriction) {
+	for _, r := range restrictions {
+		fmt.Println(r.SecurityCode, r.Type, r.Market, r.StartDate, r.EndDate, r.Reason)
+	}
+}
+
+func sqlValue(s string) string {
+	if len(s) == 0 {
+		return "null"
+	}
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+func writeRestrictions(db *sql.DB, restrictions []Restriction) bool {
+	// a security may be listed twice on the same start date, keep the last one
+	var keys []string
+	latest := make(map[string]Restriction)
+	for _, r := range restrictions {
+		key := r.SecurityCode + r.Type + r.StartDate
+		if _, ok := latest[key]; !ok {
+			keys = append(keys, key)
+		}
+		latest[key] = r
+	}
+	if len(keys) == 0 {
+		return true
+	}
+
+	sqlString := "INSERT INTO trading_restrictions (security_code, restriction_type, market, start_date, end_date, reason) VALUES\n"
+	for _, key := range keys {
+		r := latest[key]
+		sqlString += fmt.Sprintf("('%s', '%s', '%s', '%s', %s, %s),\n", r.SecurityCode, r.Type, r.Market, r.StartDate,
+			sqlValue(r.EndDate), sqlValue(r.Reason))
+	}
+	sqlString = strings.TrimRight(sqlString, ",\n")
+	sqlString += `
+ON CONFLICT (security_code, restriction_type, start_date) DO UPDATE SET
+	market = EXCLUDED.market, end_date = EXCLUDED.end_date, reason = EXCLUDED.reason;`
+	//fmt.Println(sqlString)
+	_, err := db.Exec(sqlString)
+	if err != nil {
+		log.Println("writeRestrictions", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+const urlTSEStockDay = "http://www.twse.com.tw/exchangeReport/STOCK_DAY?response=json&date=%4d%02d01&stockNo=%s"
+const urlOTCStockDay = "http://www.tpex.org.tw/web/stock/aftertrading/daily_trading_info/st43_result.php?l=zh-tw&d=%d/%02d&stkno=%s"
+const kMinDate = 20000000
+const kMaxRetry = 3
+
+const (
+	DB_USER     = "stock"
+	DB_PASSWORD = "test"
+	DB_NAME     = "stock"
+	DB_HOST     = "data.example.com"
+)
+
+type TSEStockDay struct {
+	Status string     `json:"stat"`
+	Data   [][]string `json:"data"`
+}
+
+type OTCStockDay struct {
+	Data [][]string `json:"aaData"`
+}
+
+type StockDay struct {
+	Date   string
+	Volume string
+	Amount string
+	Open   string
+	High   string
+	Low    string
+	Close  string
+	Count  string
+}
+
+var errNoData = errors.New("no data")
+
+var flagCodes = flag.String("s", "", "security codes separated by comma")
+var flagFromDate = flag.Int("f", 0, "from date YYYYMMDD (default: January 1st of this year)")
+var flagToDate = flag.Int("t", 0, "to date YYYYMMDD (default: today)")
+
+func main() {
+	flag.Parse()
+	if len(*flagCodes) == 0 {
+		flag.Usage()
+		return
+	}
+
+	dbinfo := fmt.Sprintf("user=%s password=%s dbname=%s host=%s sslmode=disable", DB_USER, DB_PASSWORD, DB_NAME, DB_HOST)
+	db, err := sql.Open("postgres", dbinfo)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	today := time.Now()
+	fromDate := *flagFromDate
+	toDate := *flagToDate
+	if fromDate == 0 || fromDate < kMinDate {
+		fromDate = today.Year()*10000 + 101
+	}
+	if toDate == 0 || toDate < kMinDate {
+		toDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+	}
+
+	log.Println(fromDate, toDate)
+
+	local, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		panic(err)
+	}
+
+	beginDate := time.Date(fromDate/10000, time.Month(fromDate%10000/100), fromDate%100, 0, 0, 0, 0, local)
+	endDate := time.Date(toDate/10000, time.Month(toDate%10000/100), toDate%100, 0, 0, 0, 0, local)
+	for _, code := range strings.Split(*flagCodes, ",") {
+		code = strings.TrimSpace(code)
+		// the market is decided by the first month with data, and only
+		// switched to OTC when TSE has no data, for stocks moved from OTC
+		otc := false
+		listed := false
+		month := time.Date(endDate.Year(), endDate.Month(), 1, 0, 0, 0, 0, local)
+		for ; month.AddDate(0, 1, 0).After(beginDate); month = month.AddDate(0, -1, 0) {
+			log.Println(code, month.Year(), int(month.Month()))
+			time.Sleep(1 * time.Second)
+			days, err := fetchStockDays(code, otc, month.Year(), int(month.Month()))
+			if err == errNoData && !otc {
+				time.Sleep(1 * time.Second)
+				if otcDays, otcErr := fetchStockDays(code, true, month.Year(), int(month.Month())); otcErr == nil {
+					days, err = otcDays, nil
+					otc = true
+				}
+			}
+			if err == errNoData {
+				if listed {
+					// before listing
+					break
+				}
+				// after delisting, or not listed at all
+				continue
+			}
+			if err != nil {
+				log.Println(code, month.Year(), int(month.Month()), err)
+				continue
+			}
+			listed = true
+			writeStockDays(db, code, beginDate, endDate, month, days)
+		}
+	}
+}
+
+// fetchStockDays retries transient errors, errNoData is returned at once.
+func fetchStockDays(code string, otc bool, year, month int) ([]StockDay, error) {
+	fetch := fetchTSEStockDays
+	if otc {
+		fetch = fetchOTCStockDays
+	}
+	var days []StockDay
+	var err error
+	for retry := 0; retry < kMaxRetry; retry++ {
+		if retry > 0 {
+			time.Sleep(time.Duration(retry*5) * time.Second)
+		}
+		days, err = fetch(code, year, month)
+		if err == nil && len(days) == 0 {
+			err = errNoData
+		}
+		if err == nil || err == errNoData {
+			break
+		}
+		log.Println(code, err)
+	}
+	return days, err
+}
+
+func httpGet(url string) ([]byte, error) {
+	log.Println(url)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("----")
+	log.Println(resp.StatusCode)
+	log.Println(resp.Status)
+	log.Println("Body len = ", len(contents))
+
+	return contents, nil
+}
+
+// rocDate converts 107/01/02 to 2018/01/02
+func rocDate(s string) string {
+	var year, month, day int
+	if n, _ := fmt.Sscanf(strings.TrimSpace(s), "%d/%d/%d", &year, &month, &day); n != 3 {
+		return ""
+	}
+	return fmt.Sprintf("%04d/%02d/%02d", year+1911, month, day)
+}
+
+func number(s string) string {
+	s = strings.Replace(strings.TrimSpace(s), ",", "", -1)
+	if strings.Contains(s, "--") || len(s) == 0 {
+		return ""
+	}
+	return s
+}
+
+// thousands converts 仟股/仟元 columns to shares/dollars
+func thousands(s string) string {
+	s = number(s)
+	if len(s) == 0 {
+		return s
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatFloat(n*1000, 'f', 0, 64)
+}
+
+// fetchTSEStockDays returns errNoData for months the security was not
+// listed on TSE, other errors are worth a retry.
+func fetchTSEStockDays(code string, year, month int) ([]StockDay, error) {
+	var stockDay TSEStockDay
+
+	contents, err := httpGet(fmt.Sprintf(urlTSEStockDay, year, month, code))
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(contents, &stockDay)
+	if err != nil {
+		return nil, err
+	}
+
+	if stockDay.Status != "OK" {
+		log.Println("stat: ", stockDay.Status)
+		return nil, errNoData
+	}
+
+	// "fields":["日期","成交股數","成交金額","開盤價","最高價","最低價","收盤價","漲跌價差","成交筆數"]
+	var days []StockDay
+	for _, data := range stockDay.Data {
+		if len(data) < 9 {
+			continue
+		}
+		days = append(days, StockDay{rocDate(data[0]), number(data[1]), number(data[2]),
+			number(data[3]), number(data[4]), number(data[5]), number(data[6]), number(data[8])})
+	}
+	return days, nil
+}
+
+// fetchOTCStockDays returns no rows for months the security was not listed
+// on OTC.
+func fetchOTCStockDays(code string, year, month int) ([]StockDay, error) {
+	var stockDay OTCStockDay
+
+	contents, err := httpGet(fmt.Sprintf(urlOTCStockDay, year-1911, month, code))
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(contents, &stockDay)
+	if err != nil {
+		return nil, err
+	}
+
+	// 日期,成交仟股,成交仟元,開盤,最高,最低,收盤,漲跌,筆數
+	var days []StockDay
+	for _, data := range stockDay.Data {
+		if len(data) < 9 {
+			continue
+		}
+		days = append(days, StockDay{rocDate(data[0]), thousands(data[1]), thousands(data[2]),
+			number(data[3]), number(data[4]), number(data[5]), number(data[6]), number(data[8])})
+	}
+	return days, nil
+}
+
+func sqlValue(s string) string {
+	if len(s) == 0 {
+		return "null"
+	}
+	return "'" + s + "'"
+}
+
+// writeStockDays fills daily_quotes with the monthly rows. Existing rows from
+// the full-market crawl are kept, only their missing columns are filled, and
+// any close price or volume differences are logged.
+func writeStockDays(db *sql.DB, code string, beginDate, endDate, month time.Time, days []StockDay) bool {
+	existing := make(map[string][2]string)
+	rows, err := db.Query("SELECT to_char(trade_date, 'YYYY/MM/DD'), COALESCE(close_price::varchar, ''), COALESCE(trade_volume::varchar, '') FROM daily_quotes WHERE security_code = $1 AND trade_date BETWEEN $2 AND $3",
+		code, month.Format("2006/01/02"), month.AddDate(0, 1, -1).Format("2006/01/02"))
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	for rows.Next() {
+		var date, closePrice, volume string
+		if err := rows.Scan(&date, &closePrice, &volume); err != nil {
+			log.Fatal(err)
+		}
+		existing[date] = [2]string{closePrice, volume}
+	}
+	rows.Close()
+
+	sqlString := "INSERT INTO daily_quotes (trade_date, security_code, trade_volume, trade_amount, open_price, highest_price, lowest_price, close_price, trade_count) VALUES\n"
+	count := 0
+	for _, day := range days {
+		date, err := time.ParseInLocation("2006/01/02", day.Date, beginDate.Location())
+		if err != nil || date.Before(beginDate) || date.After(endDate) {
+			continue
+		}
+		if values, ok := existing[day.Date]; ok {
+			// OTC monthly volume is rounded to thousand shares
+			if !sameNumber(values[0], day.Close, 0) || !sameNumber(values[1], day.Volume, 1000) {
+				log.Printf("%s %s mismatch close %s/%s volume %s/%s\n", code, day.Date, values[0], day.Close, values[1], day.Volume)
+			}
+		}
+		sqlString += fmt.Sprintf("('%s', '%s', %s, %s, %s, %s, %s, %s, %s),\n", day.Date, code,
+			sqlValue(day.Volume), sqlValue(day.Amount), sqlValue(day.Open), sqlValue(day.High),
+			sqlValue(day.Low), sqlValue(day.Close), sqlValue(day.Count))
+		count++
+	}
+	if count == 0 {
+		return true
+	}
+	sqlString = strings.TrimRight(sqlString, ",\n")
+	sqlString += `
+ON CONFLICT (trade_date, security_code) DO UPDATE SET
+	trade_volume = COALESCE(daily_quotes.trade_volume, EXCLUDED.trade_volume),
+	trade_amount = COALESCE(daily_quotes.trade_amount, EXCLUDED.trade_amount),
+	open_price = COALESCE(daily_quotes.open_price, EXCLUDED.open_price),
+	highest_price = COALESCE(daily_quotes.highest_price, EXCLUDED.highest_price),
+	lowest_price = COALESCE(daily_quotes.lowest_price, EXCLUDED.lowest_price),
+	close_price = COALESCE(daily_quotes.close_price, EXCLUDED.close_price),
+	trade_count = COALESCE(daily_quotes.trade_count, EXCLUDED.trade_count);`
+	//fmt.Println(sqlString)
+	_, err = db.Exec(sqlString)
+	if err != nil {
+		log.Println("writeStockDays", err)
+		return false
+	}
+	return true
+}
+
+func sameNumber(a, b string, tolerance float64) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	x, err := strconv.ParseFloat(a, 64)
+	if err != nil {
+		return false
+	}
+	y, err := strconv.ParseFloat(b, 64)
+	if err != nil {
+		return false
+	}
+	return math.Abs(x-y) <= tolerance
+}
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/transform"
+)
+
+var enc = traditionalchinese.Big5
+
+// type=0999 call warrants, 0999P put warrants, 0999C bull CBBC, 0999B bear
+// CBBC, 0999X extendable bull CBBC, 0999Y extendable bear CBBC
+const urlTSEWarrantQuote = "http://www.twse.com.tw/exchangeReport/MI_INDEX?response=json&date=%4d%02d%02d&type=%s"
+const urlOTCWarrantQuote = "http://www.tpex.org.tw/web/stock/aftertrading/otc_quotes_no1430/stk_wn1430_download.php?l=zh-tw&d=%d/%02d/%02d&se=AL&s=0,asc,0"
+
+// warrant basic information (underlying, strike, expiry, exercise ratio), UTF-8 CSV.
+// The ISIN list (isin.twse.com.tw C_public.jsp) only has the code, name,
+// listing date and CFI of a warrant, not its terms, so they come from the
+// MOPS open data instead.
+const urlTSEWarrantInfo = "http://mopsfin.twse.com.tw/opendata/t187ap37_L.csv"
+const urlOTCWarrantInfo = "http://mopsfin.twse.com.tw/opendata/t187ap37_O.csv"
+
+const kMinSize = 1024
+const kMinDate = 20000000
+
+const (
+	DB_USER     = "stock"
+	DB_PASSWORD = "test"
+	DB_NAME     = "stock"
+	DB_HOST     = "data.example.com"
+)
+
+const deleteSql = "DELETE FROM warrant_quotes WHERE trade_date = $1"
+
+var tseWarrantTypes = [...]string{"0999", "0999P", "0999C", "0999B", "0999X", "0999Y"}
+
+// warrant and CBBC codes, TSE 03xxxx-08xxxx and OTC 70xxxx-73xxxx, puts end
+// in P, bull and bear CBBCs in C and B, extendable ones in X and Y
+var warrantCode = regexp.MustCompile(`^(0[3-8]|7[0-3])[0-9]{3}[0-9PCBXY]$`)
+
+// errNoData is returned for holidays and types not issued yet.
+var errNoData = errors.New("no data")
+
+type WarrantQuote struct {
+	Status string     `json:"stat"`
+	Date   string     `json:"date"`
+	Fields []string   `json:"fields1"`
+	Data   [][]string `json:"data1"`
+}
+
+type WarrantInfo struct {
+	Underlying    string
+	StrikePrice   string
+	ExpiryDate    string
+	ExerciseRatio string
+}
+
+var warrantInfos map[string]WarrantInfo
+
+var flagFromDate = flag.Int("f", 0, "from date YYYYMMDD (default: the day after latest trade date in DB)")
+var flagToDate = flag.Int("t", 0, "to date YYYYMMDD (default: today)")
+var flagLastTradeDay = flag.Bool("l", false, "last trade day only")
+
+func main() {
+	flag.Parse()
+	dbinfo := fmt.Sprintf("user=%s password=%s dbname=%s host=%s sslmode=disable", DB_USER, DB_PASSWORD, DB_NAME, DB_HOST)
+	db, err := sql.Open("postgres", dbinfo)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	warrantInfos = make(map[string]WarrantInfo)
+	fetchWarrantInfos(urlTSEWarrantInfo, warrantInfos)
+	fetchWarrantInfos(urlOTCWarrantInfo, warrantInfos)
+	if len(warrantInfos) == 0 {
+		log.Println("no warrant information")
+		return
+	}
+
+	today := time.Now()
+	fromDate := *flagFromDate
+	toDate := *flagToDate
+	if fromDate == 0 || fromDate < kMinDate {
+		fromDate, err = fetchLastTradeDate(db)
+		if err != nil {
+			fromDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+		}
+	}
+	if toDate == 0 || toDate < kMinDate {
+		toDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+	}
+
+	log.Println(fromDate, toDate)
+
+	local, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		panic(err)
+	}
+
+	var quoteDate, beginDate time.Time
+	if *flagLastTradeDay {
+		quoteDate = today
+	} else {
+		quoteDate = time.Date(toDate/10000, time.Month(toDate%10000/100), toDate%100, 1, 0, 0, 0, local)
+	}
+	beginDate = time.Date(fromDate/10000, time.Month(fromDate%10000/100), fromDate%100, 0, 0, 0, 0, local)
+	log.Println("beginDate = ", beginDate)
+	for quoteDate.After(beginDate) {
+		log.Println(quoteDate)
+		// types without data are left out, CBBCs (0999C/0999B) only exist
+		// since 2011.  The date is replaced as a whole, so it is skipped
+		// when any fetch fails rather than losing the stored rows of a type.
+		var quotes []*WarrantQuote
+		failed := false
+		for _, warrantType := range tseWarrantTypes {
+			time.Sleep(1 * time.Second)
+			quote, err := fetchTSEWarrantQuotes(quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day(), warrantType)
+			if err == errNoData {
+				log.Println("no data of type", warrantType)
+				continue
+			}
+			if err != nil {
+				log.Println(warrantType, err)
+				failed = true
+				break
+			}
+			quotes = append(quotes, quote)
+		}
+		csvString, ok := "", false
+		if !failed {
+			var err error
+			csvString, err = fetchOTCWarrantQuotes(quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day())
+			if err != nil && err != errNoData {
+				log.Println("OTC", err)
+				failed = true
+			}
+			ok = err == nil
+		}
+		if failed {
+			log.Println("skip", quoteDate.Format("2006/01/02"))
+		} else if len(quotes) > 0 || ok {
+			tradeDate := fmt.Sprintf("%04d/%02d/%02d", quoteDate.Year(), int(quoteDate.Month()), quoteDate.Day())
+			_, err := db.Exec(deleteSql, tradeDate)
+			if err == nil || err == sql.ErrNoRows {
+				for _, quote := range quotes {
+					writeTSEWarrantQuotes(db, tradeDate, quote)
+				}
+				if ok {
+					writeOTCWarrantQuotes(db, tradeDate, csvString)
+				}
+			} else {
+				log.Printf("DELETE error = %v\n", err)
+			}
+			if *flagLastTradeDay {
+				break
+			}
+		}
+		quoteDate = quoteDate.AddDate(0, 0, -1)
+	}
+}
+
+func fetchLastTradeDate(db *sql.DB) (int, error) {
+	var row1 string
+	sqlString := "SELECT to_char(MAX(trade_date)+interval '1 day', 'YYYYMMDD') FROM warrant_quotes"
+	err := db.QueryRow(sqlString).Scan(&row1)
+	if err != nil {
+		return 0, err
+	}
+	log.Println("the day after latest trade day = ", row1)
+
+	return strconv.Atoi(row1)
+}
+
+// fetchWarrantInfos reads the warrant basic information list.  Columns are
+// located by header name since the open data file changes its layout.
+func fetchWarrantInfos(url string, infos map[string]WarrantInfo) bool {
+	log.Println(url)
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+
+	csvr := csv.NewReader(resp.Body)
+	csvr.FieldsPerRecord = -1
+	records, err := csvr.ReadAll()
+	if err != nil || len(records) < 2 {
+		log.Println(err)
+		return false
+	}
+
+	header := records[0]
+	codeIndex := columnIndex(header, "權證代號")
+	underlyingIndex := columnIndex(header, "標的代號", "標的證券代號")
+	strikeIndex := columnIndex(header, "最新履約價格", "履約價格")
+	expiryIndex := columnIndex(header, "履約截止日", "到期日")
+	ratioIndex := columnIndex(header, "最新標的履約配發數量", "行使比例")
+	if codeIndex < 0 || underlyingIndex < 0 || strikeIndex < 0 || expiryIndex < 0 || ratioIndex < 0 {
+		log.Println("unknown warrant information header", header)
+		return false
+	}
+
+	for _, record := range records[1:] {
+		if len(record) < len(header) {
+			continue
+		}
+		var info WarrantInfo
+		info.Underlying = strings.TrimSpace(record[underlyingIndex])
+		info.StrikePrice = strings.Replace(strings.TrimSpace(record[strikeIndex]), ",", "", -1)
+		info.ExpiryDate = rocDate(record[expiryIndex])
+		info.ExerciseRatio = strings.Replace(strings.TrimSpace(record[ratioIndex]), ",", "", -1)
+		infos[strings.TrimSpace(record[codeIndex])] = info
+	}
+
+	return true
+}
+
+func columnIndex(header []string, names ...string) int {
+	for _, name := range names {
+		for i, field := range header {
+			if strings.Contains(field, name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// rocDate converts 1071231 or 107/12/31 to 2018/12/31
+func rocDate(s string) string {
+	s = strings.Replace(strings.TrimSpace(s), "/", "", -1)
+	date, err := strconv.Atoi(s)
+	if err != nil || date == 0 {
+		return ""
+	}
+	if date < kMinDate {
+		date += 19110000
+	}
+	return fmt.Sprintf("%04d/%02d/%02d", date/10000, date%10000/100, date%100)
+}
+
+// fetchTSEWarrantQuotes returns errNoData for days or types without
+// quotes, other errors when the report could not be read.
+func fetchTSEWarrantQuotes(year, month, day int, warrantType string) (*WarrantQuote, error) {
+	var url string
+	var contents []byte
+	var quote WarrantQuote
+
+	url = fmt.Sprintf(urlTSEWarrantQuote, year, month, day, warrantType)
+	log.Println(url)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+
+	contents, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("----")
+	log.Println(resp.StatusCode)
+	log.Println(resp.Status)
+	log.Println("Body len = ", len(contents))
+
+	if len(contents) < kMinSize {
+		return nil, errNoData
+	}
+
+	err = json.Unmarshal(contents, &quote)
+	if err != nil {
+		return nil, err
+	}
+
+	if quote.Status != "OK" {
+		log.Println("stat: ", quote.Status)
+		return nil, errNoData
+	}
+
+	return &quote, nil
+}
+
+func fetchOTCWarrantQuotes(year, month, day int) (string, error) {
+	var url string
+
+	url = fmt.Sprintf(urlOTCWarrantQuote, year-1911, month, day)
+	log.Println(url)
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(resp.Status)
+	}
+
+	log.Println("----")
+	log.Println(resp.StatusCode)
+	log.Println(resp.Status)
+
+	r := transform.NewReader(resp.Body, enc.NewDecoder())
+	input := bufio.NewScanner(r)
+
+	lineCount := 0
+	out := ""
+	for input.Scan() {
+		in := strings.TrimSpace(input.Text())
+		if len(in) <= 20 {
+			continue
+		}
+		lineCount++
+		if lineCount > 4 {
+			out += in + "\n"
+		}
+	}
+
+	if err := input.Err(); err != nil {
+		return "", err
+	}
+	if lineCount < 2 {
+		return "", errNoData
+	}
+
+	return out, nil
+}
+
+// warrantValues returns the joined underlying, strike, expiry and exercise
+// ratio columns, all null when the warrant is no longer listed.
+func warrantValues(code string) string {
+	info, ok := warrantInfos[code]
+	if !ok {
+		return " null, null, null, null"
+	}
+	values := ""
+	for i, field := range []string{info.Underlying, info.StrikePrice, info.ExpiryDate, info.ExerciseRatio} {
+		if len(field) == 0 {
+			values += " null"
+		} else {
+			values += " '" + field + "'"
+		}
+		if i != 3 {
+			values += ","
+		}
+	}
+	return values
+}
+
+func writeTSEWarrantQuotes(db *sql.DB, tradeDate string, quotes *WarrantQuote) bool {
+	if len(quotes.Data) == 0 {
+		return true
+	}
+
+	// "fields1":["證券代號","證券名稱","成交股數","成交筆數","成交金額","開盤價","最高價","最低價","收盤價","漲跌(+/-)","漲跌價差","最後揭示買價","最後揭示買量","最後揭示賣價","最後揭示賣量","本益比"]
+	sqlString := "INSERT INTO warrant_quotes (trade_date, security_code, trade_volume, trade_count, trade_amount, open_price, highest_price, lowest_price, close_price, last_bid_price, last_bid_volume, last_ask_price, last_ask_volume, underlying_code, strike_price, expiry_date, exercise_ratio) VALUES\n"
+	for _, quote := range quotes.Data {
+		sqlString += fmt.Sprintf("('%s',", tradeDate)
+		for i := 0; i < len(quote); i++ {
+			if i == 1 || i == 9 || i == 10 || i == 15 {
+				continue
+			}
+			if strings.Contains(quote[i], "--") || len(quote[i]) == 0 {
+				sqlString += " null"
+			} else {
+				sqlString += " '" + strings.Replace(quote[i], ",", "", -1) + "'"
+			}
+			sqlString += ","
+		}
+		sqlString += warrantValues(strings.TrimSpace(quote[0]))
+		sqlString += "),\n"
+	}
+	sqlString = strings.TrimRight(sqlString, ",\n") + ";"
+	//fmt.Println(sqlString)
+	_, err := db.Exec(sqlString)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+func writeOTCWarrantQuotes(db *sql.DB, tradeDate string, csvString string) bool {
+	csvr := csv.NewReader(strings.NewReader(csvString))
+
+	records, err := csvr.ReadAll()
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+
+	// 代號,名稱,收盤 ,漲跌,開盤 ,最高 ,最低,成交股數  , 成交金額(元), 成交筆數 ,最後買價,最後賣價,發行股數 ,次日漲停價 ,次日跌停價
+	sqlString := "INSERT INTO warrant_quotes (trade_date, security_code, close_price, open_price, highest_price, lowest_price, trade_volume, trade_amount, trade_count, last_bid_price, last_bid_volume, last_ask_price, last_ask_volume, underlying_code, strike_price, expiry_date, exercise_ratio) VALUES\n"
+
+	count := 0
+	for _, record := range records {
+		// se=AL lists every security, keep only warrants and CBBCs.  Expired
+		// warrants are not in warrantInfos and get null terms.
+		if len(record) < 12 {
+			continue
+		}
+		code := strings.TrimSpace(record[0])
+		if !warrantCode.MatchString(code) {
+			continue
+		}
+		count++
+		sqlString += fmt.Sprintf("('%s',", tradeDate)
+
+		for i, field := range record {
+			if i == 1 || i == 3 {
+				continue
+			}
+
+			if strings.Contains(field, "--") || len(field) == 0 {
+				sqlString += " null"
+			} else {
+				sqlString += " '" + strings.Replace(field, ",", "", -1) + "'"
+			}
+			sqlString += ","
+			if i == 10 {
+				sqlString += " null,"
+			}
+			if i == 11 {
+				sqlString += " null,"
+				break
+			}
+		}
+		sqlString += warrantValues(code)
+		sqlString += "),\n"
+	}
+
+	if count == 0 {
+		return true
+	}
+
+	sqlString = strings.TrimRight(sqlString, ",\n") + ";"
+	//fmt.Println(sqlString)
+	_, err = db.Exec(sqlString)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
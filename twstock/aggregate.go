@@ -0,0 +1,213 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// period rolls daily_quotes into bars, trade_date of a bar is the first day
+// of its period.
+type period struct {
+	name  string
+	table string
+	start func(date time.Time) time.Time
+	next  func(start time.Time) time.Time
+}
+
+var periods = []period{
+	{"weekly", "weekly_quotes", weekStart, func(start time.Time) time.Time { return start.AddDate(0, 0, 7) }},
+	{"monthly", "monthly_quotes", monthStart, func(start time.Time) time.Time { return start.AddDate(0, 1, 0) }},
+}
+
+var periodQuoteColumns = []string{"security_code", "open_price", "highest_price", "lowest_price", "close_price", "trade_volume", "trade_amount", "trade_count", "trade_days"}
+
+// weekStart returns the Monday of the week.
+func weekStart(date time.Time) time.Time {
+	offset := (int(date.Weekday()) + 6) % 7
+	return time.Date(date.Year(), date.Month(), date.Day()-offset, 0, 0, 0, 0, date.Location())
+}
+
+func monthStart(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+}
+
+func init() {
+	addCommand("aggregate", "build weekly_quotes and monthly_quotes from daily_quotes", runAggregate)
+}
+
+func runAggregate(args []string) {
+	flags := flag.NewFlagSet("aggregate", flag.ExitOnError)
+	flagStore := flags.String("db", "postgres", "store: postgres or sqlite")
+	flagDSN := flags.String("dsn", "", "data source name (default: the shared database, or "+defaultSQLiteFile+" for sqlite)")
+	flagPeriods := flags.String("p", "weekly,monthly", "periods separated by comma")
+	flagFromDate := flags.Int("f", 0, "from date YYYYMMDD (default: latest trade date in daily_quotes)")
+	flagToDate := flags.Int("t", 0, "to date YYYYMMDD (default: latest trade date in daily_quotes)")
+	flags.Parse(args)
+
+	store, err := openStore(*flagStore, *flagDSN)
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	defer store.Close()
+	reader, ok := store.(Reader)
+	rs, ok2 := store.(rowStore)
+	if !ok || !ok2 {
+		log.Println(*flagStore, "can not be aggregated")
+		os.Exit(1)
+	}
+
+	fromDate := *flagFromDate
+	toDate := *flagToDate
+	if fromDate < kMinDate || toDate < kMinDate {
+		lastDate, err := store.LastTradeDate("daily_quotes")
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		if fromDate < kMinDate {
+			fromDate = lastDate
+		}
+		if toDate < kMinDate {
+			toDate = lastDate
+		}
+	}
+	from := time.Date(fromDate/10000, time.Month(fromDate%10000/100), fromDate%100, 0, 0, 0, 0, time.UTC)
+	to := time.Date(toDate/10000, time.Month(toDate%10000/100), toDate%100, 0, 0, 0, 0, time.UTC)
+
+	for _, name := range strings.Split(*flagPeriods, ",") {
+		name = strings.TrimSpace(name)
+		var p *period
+		for i := range periods {
+			if periods[i].name == name {
+				p = &periods[i]
+			}
+		}
+		if p == nil {
+			log.Println("unknown period", name)
+			continue
+		}
+		for start := p.start(from); !start.After(to); start = p.next(start) {
+			count, err := aggregatePeriod(reader, rs, *p, start)
+			if err != nil {
+				log.Println(p.table, start.Format("2006-01-02"), err)
+				continue
+			}
+			log.Println(p.table, start.Format("2006-01-02"), count, "rows")
+		}
+	}
+}
+
+// aggregateDate rebuilds the bars containing date, crawl and import call it
+// after writing daily_quotes.  Stores which can not be read are skipped.
+func aggregateDate(store Store, date time.Time) error {
+	reader, ok := store.(Reader)
+	rs, ok2 := store.(rowStore)
+	if !ok || !ok2 {
+		return nil
+	}
+	for _, p := range periods {
+		if _, err := aggregatePeriod(reader, rs, p, p.start(date)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bar is one security in one period, prices keep the strings of
+// daily_quotes.
+type bar struct {
+	open, high, low, close string
+	highValue, lowValue    float64
+	volume, amount, count  float64
+	days                   int
+}
+
+func (b *bar) add(open, high, low, close, volume, amount, count sql.NullString) {
+	if open.Valid && len(b.open) == 0 {
+		b.open = open.String
+	}
+	if v, err := strconv.ParseFloat(high.String, 64); err == nil && (len(b.high) == 0 || v > b.highValue) {
+		b.high, b.highValue = high.String, v
+	}
+	if v, err := strconv.ParseFloat(low.String, 64); err == nil && (len(b.low) == 0 || v < b.lowValue) {
+		b.low, b.lowValue = low.String, v
+	}
+	if close.Valid {
+		b.close = close.String
+		b.days++
+	}
+	if v, err := strconv.ParseFloat(volume.String, 64); err == nil {
+		b.volume += v
+	}
+	if v, err := strconv.ParseFloat(amount.String, 64); err == nil {
+		b.amount += v
+	}
+	if v, err := strconv.ParseFloat(count.String, 64); err == nil {
+		b.count += v
+	}
+}
+
+func (b *bar) values(code string) []string {
+	return []string{code, b.open, b.high, b.low, b.close,
+		strconv.FormatFloat(b.volume, 'f', -1, 64),
+		strconv.FormatFloat(b.amount, 'f', -1, 64),
+		strconv.FormatFloat(b.count, 'f', -1, 64),
+		strconv.Itoa(b.days)}
+}
+
+// aggregatePeriod replaces the bars of the period starting at start.
+func aggregatePeriod(reader Reader, rs rowStore, p period, start time.Time) (int, error) {
+	end := p.next(start).AddDate(0, 0, -1)
+	columns := []string{"security_code", "open_price", "highest_price", "lowest_price", "close_price", "trade_volume", "trade_amount", "trade_count"}
+	rows, err := reader.ReadRows("daily_quotes", rowQuery{from: start, to: end, columns: columns})
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	index := make(map[string]int)
+	values := make([]sql.NullString, len(columns))
+	scanArgs := make([]interface{}, len(columns))
+	for i, column := range columns {
+		index[column] = i
+		scanArgs[i] = &values[i]
+	}
+
+	// rows come ordered by trade_date
+	bars := make(map[string]*bar)
+	for rows.Next() {
+		if err := rows.Scan(scanArgs...); err != nil {
+			return 0, err
+		}
+		code := values[index["security_code"]].String
+		b, ok := bars[code]
+		if !ok {
+			b = &bar{}
+			bars[code] = b
+		}
+		b.add(values[index["open_price"]], values[index["highest_price"]], values[index["lowest_price"]], values[index["close_price"]],
+			values[index["trade_volume"]], values[index["trade_amount"]], values[index["trade_count"]])
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	codes := make([]string, 0, len(bars))
+	for code := range bars {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	out := make([][]string, len(codes))
+	for i, code := range codes {
+		out[i] = bars[code].values(code)
+	}
+	return len(out), rs.replaceRows(p.table, start, periodQuoteColumns, out)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWeekStart(t *testing.T) {
+	tests := []struct {
+		date, want string
+	}{
+		{"2018-06-25", "2018-06-25"}, // Monday
+		{"2018-06-29", "2018-06-25"},
+		{"2018-07-01", "2018-06-25"}, // Sunday
+		{"2018-03-01", "2018-02-26"}, // across a month
+		{"2019-01-02", "2018-12-31"}, // across a year
+	}
+	for _, tt := range tests {
+		date, _ := time.Parse("2006-01-02", tt.date)
+		if got := weekStart(date).Format("2006-01-02"); got != tt.want {
+			t.Errorf("weekStart(%s) = %s, want %s", tt.date, got, tt.want)
+		}
+	}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: len(s) > 0}
+}
+
+func TestBarAdd(t *testing.T) {
+	tests := []struct {
+		name string
+		days [][7]string // open, high, low, close, volume, amount, count
+		want []string
+	}{
+		{
+			"first open and last close",
+			[][7]string{
+				{"10", "11", "9.5", "10.5", "1000", "10000", "10"},
+				{"10.5", "12", "10", "11.5", "2000", "22000", "20"},
+				{"11.5", "11.5", "8", "9", "3000", "27000", "30"},
+			},
+			[]string{"2330", "10", "12", "8", "9", "6000", "59000", "60", "3"},
+		},
+		{
+			"nulls of days without trades",
+			[][7]string{
+				{"", "", "", "", "0", "0", "0"},
+				{"20", "21", "19", "20.5", "500", "10000", "5"},
+				{"", "", "", "", "", "", ""},
+			},
+			[]string{"2330", "20", "21", "19", "20.5", "500", "10000", "5", "1"},
+		},
+		{
+			"numbers compared as numbers",
+			[][7]string{
+				{"9.5", "9.5", "9.5", "9.5", "1", "1", "1"},
+				{"10", "10", "10", "10", "1", "1", "1"},
+			},
+			[]string{"2330", "9.5", "10", "9.5", "10", "2", "2", "2", "2"},
+		},
+		{
+			"large volumes in plain decimals",
+			[][7]string{
+				{"225.5", "226", "224", "225", "12345678", "2780000000", "9876"},
+			},
+			[]string{"2330", "225.5", "226", "224", "225", "12345678", "2780000000", "9876", "1"},
+		},
+	}
+	for _, tt := range tests {
+		var b bar
+		for _, d := range tt.days {
+			b.add(nullString(d[0]), nullString(d[1]), nullString(d[2]), nullString(d[3]), nullString(d[4]), nullString(d[5]), nullString(d[6]))
+		}
+		if got := b.values("2330"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// SQLite returns numeric columns as float64, the bars have to keep the
+// decimal text.
+func TestAggregateSQLite(t *testing.T) {
+	store, err := openSQLite(filepath.Join(t.TempDir(), "twstock.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	s := store.(*sqlStore)
+
+	monday := time.Date(2018, 6, 25, 0, 0, 0, 0, time.UTC)
+	for i, close := range []string{"225.5", "226"} {
+		date := monday.AddDate(0, 0, i)
+		quote := Quote{SecurityCode: "2330", Volume: "12345678", Count: "9876", Amount: "2780000000.5",
+			Open: "225", High: "227", Low: "224", Close: close}
+		if err := store.WriteQuotes(date, []Quote{quote}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a REAL is scanned as 2.7800000005e+09 without the cast
+	rows, err := s.ReadRows("daily_quotes", rowQuery{from: monday, to: monday, columns: []string{"trade_amount"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var amount string
+	for rows.Next() {
+		if err := rows.Scan(&amount); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rows.Close()
+	if amount != "2780000000.5" {
+		t.Errorf("trade_amount %s, want 2780000000.5", amount)
+	}
+
+	if _, err := aggregatePeriod(s, s, periods[0], monday); err != nil {
+		t.Fatal(err)
+	}
+	var open, high, close, volume, amounts, days string
+	err = s.db.QueryRow("SELECT CAST(open_price AS text), CAST(highest_price AS text), CAST(close_price AS text), CAST(trade_volume AS text), CAST(trade_amount AS text), CAST(trade_days AS text) FROM weekly_quotes WHERE trade_date = '2018-06-25'").
+		Scan(&open, &high, &close, &volume, &amounts, &days)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := []string{open, high, close, volume, amounts, days}; !reflect.DeepEqual(got, []string{"225", "227", "226", "24691356", "5560000001", "2"}) {
+		t.Errorf("weekly bar %v", got)
+	}
+}
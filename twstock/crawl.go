@@ -0,0 +1,208 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+const kMinDate = 20000000
+
+type dataset struct {
+	name    string
+	table   string
+	columns []string
+	// crawl fetches one trade date and writes it to store, it returns the
+	// number of rows or errNoData.
+	crawl func(store Store, date time.Time) (int, error)
+	// published is the usual time of day the exchanges publish a trade
+	// date, Asia/Taipei
+	published time.Duration
+}
+
+var datasets = []dataset{
+	{"quotes", "daily_quotes", quoteColumns, crawlQuotes, 14 * time.Hour},
+	{"investors", "daily_investors", investorColumns, crawlInvestors, 15 * time.Hour},
+	{"margin", "daily_margin_short", marginShortColumns, crawlMarginShort, 21 * time.Hour},
+}
+
+func init() {
+	addCommand("crawl", "fetch daily quotes, investors and margin into a store", runCrawl)
+}
+
+func runCrawl(args []string) {
+	flags := flag.NewFlagSet("crawl", flag.ExitOnError)
+	flagStore := flags.String("db", "postgres", "store: postgres, sqlite, or jsonl/csv files without any database")
+	flagDSN := flags.String("dsn", "", "data source name (default: the shared database, "+defaultSQLiteFile+" for sqlite, or the "+defaultDataDir+" directory for files)")
+	flagDatasets := flags.String("d", "quotes,investors,margin", "datasets separated by comma")
+	flagFromDate := flags.Int("f", 0, "from date YYYYMMDD (default: the day after latest trade date in store)")
+	flagToDate := flags.Int("t", 0, "to date YYYYMMDD (default: today)")
+	flagLastTradeDay := flags.Bool("l", false, "last trade day only")
+	flags.Parse(args)
+
+	store, err := openStore(*flagStore, *flagDSN)
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	for _, d := range selectDatasets(*flagDatasets) {
+		crawlDates(store, d, *flagFromDate, *flagToDate, *flagLastTradeDay)
+	}
+}
+
+func selectDatasets(names string) []dataset {
+	var out []dataset
+	for _, name := range strings.Split(names, ",") {
+		found := false
+		for _, d := range datasets {
+			if d.name == strings.TrimSpace(name) {
+				out = append(out, d)
+				found = true
+			}
+		}
+		if !found {
+			log.Println("unknown dataset", name)
+		}
+	}
+	return out
+}
+
+func crawlDates(store Store, d dataset, fromDate, toDate int, lastTradeDay bool) {
+	local, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		panic(err)
+	}
+
+	today := time.Now().In(local)
+	if fromDate == 0 || fromDate < kMinDate {
+		fromDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+		if lastDate, err := store.LastTradeDate(d.table); err == nil {
+			last := time.Date(lastDate/10000, time.Month(lastDate%10000/100), lastDate%100, 0, 0, 0, 0, local).AddDate(0, 0, 1)
+			fromDate = last.Year()*10000 + int(last.Month())*100 + last.Day()
+		}
+	}
+	if toDate == 0 || toDate < kMinDate {
+		toDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+	}
+
+	log.Println(d.name, fromDate, toDate)
+
+	var quoteDate, beginDate time.Time
+	if lastTradeDay {
+		quoteDate = time.Date(today.Year(), today.Month(), today.Day(), 1, 0, 0, 0, local)
+	} else {
+		quoteDate = time.Date(toDate/10000, time.Month(toDate%10000/100), toDate%100, 1, 0, 0, 0, local)
+	}
+	beginDate = time.Date(fromDate/10000, time.Month(fromDate%10000/100), fromDate%100, 0, 0, 0, 0, local)
+	for quoteDate.After(beginDate) {
+		log.Println(d.name, quoteDate.Format("2006-01-02"))
+		time.Sleep(1 * time.Second)
+		date := time.Date(quoteDate.Year(), quoteDate.Month(), quoteDate.Day(), 0, 0, 0, 0, local)
+		count, err := d.crawl(store, date)
+		if err != nil && err != errNoData {
+			log.Println(d.name, err)
+		} else if err == nil {
+			log.Println(d.name, count, "rows")
+		}
+		if lastTradeDay {
+			break
+		}
+		quoteDate = quoteDate.AddDate(0, 0, -1)
+	}
+}
+
+func crawlQuotes(store Store, date time.Time) (int, error) {
+	quotes, err := fetchTSEQuotes(date)
+	otcQuotes, shares, err2 := fetchOTCQuotes(date)
+	markets := []marketRows{{"tse", err, quoteRows(quotes)}, {"otc", err2, quoteRows(otcQuotes)}}
+	if err := fetchError(markets); err != nil {
+		recordStatus(store, "quotes", date, markets, nil)
+		return 0, err
+	}
+	quotes = append(quotes, otcQuotes...)
+	err = store.WriteQuotes(date, quotes)
+	recordStatus(store, "quotes", date, markets, err)
+	if err != nil {
+		return 0, err
+	}
+	if ss, ok := store.(sharesStore); ok {
+		if err := ss.writeShares(date, shares); err != nil {
+			return 0, err
+		}
+	}
+	if vs, ok := store.(quoteViewStore); ok {
+		if err := vs.refreshQuoteViews(); err != nil {
+			return 0, err
+		}
+	}
+	return len(quotes), aggregateDate(store, date)
+}
+
+// sharesStore is implemented by every store, shares_outstanding also holds
+// the TSE rows of MOPS/sharesoutstanding.go, so the trade date is updated
+// rather than replaced.
+type sharesStore interface {
+	writeShares(date time.Time, shares [][]string) error
+}
+
+var sharesColumns = []string{"security_code", "shares"}
+
+func (s *sqlStore) writeShares(date time.Time, shares [][]string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO shares_outstanding (trade_date, security_code, shares) VALUES (" + s.placeholder(1) + ", " + s.placeholder(2) + ", " + s.placeholder(3) + ")" +
+		" ON CONFLICT (trade_date, security_code) DO UPDATE SET shares = excluded.shares")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	tradeDate := date.Format("2006-01-02")
+	for _, row := range shares {
+		if _, err := stmt.Exec(tradeDate, row[0], row[1]); err != nil {
+			return fmt.Errorf("shares_outstanding %s: %v", row[0], err)
+		}
+	}
+	return tx.Commit()
+}
+
+// quoteViewStore is implemented by the database stores, adjusted_quotes
+// of migration 0005 is a materialized view over daily_quotes.
+type quoteViewStore interface {
+	refreshQuoteViews() error
+}
+
+func crawlInvestors(store Store, date time.Time) (int, error) {
+	investors, err := fetchTSEInvestors(date)
+	otcInvestors, err2 := fetchOTCInvestors(date)
+	markets := []marketRows{{"tse", err, investorRows(investors)}, {"otc", err2, investorRows(otcInvestors)}}
+	if err := fetchError(markets); err != nil {
+		recordStatus(store, "investors", date, markets, nil)
+		return 0, err
+	}
+	investors = append(investors, otcInvestors...)
+	err = store.WriteInvestors(date, investors)
+	recordStatus(store, "investors", date, markets, err)
+	return len(investors), err
+}
+
+func crawlMarginShort(store Store, date time.Time) (int, error) {
+	margins, err := fetchTSEMarginShort(date)
+	markets := []marketRows{{"tse", err, marginShortRows(margins)}}
+	if err != nil {
+		recordStatus(store, "margin", date, markets, nil)
+		return 0, err
+	}
+	err = store.WriteMarginShort(date, margins)
+	recordStatus(store, "margin", date, markets, err)
+	return len(margins), err
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// first wait after a try found nothing, doubled up to maxRetryDelay
+	retryDelay    = 5 * time.Minute
+	maxRetryDelay = 30 * time.Minute
+	// how long after the publication time a trade date is still polled
+	pollWindow = 6 * time.Hour
+	// how far back a daemon started late looks for a missed trade date
+	maxCatchUpDays = 7
+
+	urlTSEHolidays = "http://www.twse.com.tw/holidaySchedule/holidaySchedule?response=json&queryYear=%d"
+)
+
+// crawlRun is one row of crawl_runs.
+type crawlRun struct {
+	dataset    string
+	tradeDate  time.Time
+	startedAt  time.Time
+	finishedAt time.Time
+	attempts   int
+	status     string // ok / no_data / error
+	rowCount   int
+	err        string
+}
+
+// runRecorder is implemented by the database stores.
+type runRecorder interface {
+	recordRun(run *crawlRun) error
+}
+
+func (s *sqlStore) recordRun(run *crawlRun) error {
+	placeholders := make([]string, 8)
+	for i := range placeholders {
+		placeholders[i] = s.placeholder(i + 1)
+	}
+	var runErr interface{}
+	if len(run.err) > 0 {
+		runErr = run.err
+	}
+	_, err := s.db.Exec("INSERT INTO crawl_runs (dataset, trade_date, started_at, finished_at, attempts, status, row_count, error) VALUES ("+strings.Join(placeholders, ", ")+")",
+		run.dataset, run.tradeDate.Format("2006-01-02"), run.startedAt, run.finishedAt, run.attempts, run.status, run.rowCount, runErr)
+	return err
+}
+
+func init() {
+	addCommand("daemon", "crawl every dataset after the exchanges publish it", runDaemon)
+}
+
+func runDaemon(args []string) {
+	flags := flag.NewFlagSet("daemon", flag.ExitOnError)
+	flagStore := flags.String("db", "postgres", "store: postgres, sqlite, or jsonl/csv files without any database")
+	flagDSN := flags.String("dsn", "", "data source name (default: the shared database, "+defaultSQLiteFile+" for sqlite, or the "+defaultDataDir+" directory for files)")
+	flagDatasets := flags.String("d", "quotes,investors,margin", "datasets separated by comma")
+	flags.Parse(args)
+
+	local, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		panic(err)
+	}
+
+	store, err := openStore(*flagStore, *flagDSN)
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	// one crawl at a time, for the exchanges and for sqlite
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	cal := &calendar{days: map[int]map[string]bool{}}
+	for _, d := range selectDatasets(*flagDatasets) {
+		wg.Add(1)
+		go func(d dataset) {
+			defer wg.Done()
+			scheduleDataset(store, d, local, cal, &mu, realClock{})
+		}(d)
+	}
+	wg.Wait()
+}
+
+// clock is the time of the daemon, the tests run it on a fake one.
+type clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time        { return time.Now() }
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// calendar keeps the days of the TWSE schedule by year, true for the
+// Saturdays made up for holidays and false for holidays.
+type calendar struct {
+	mu   sync.Mutex
+	days map[int]map[string]bool
+}
+
+// tradingDay tells if the exchanges open on date, the weekdays when the
+// schedule can not be read.
+func (c *calendar) tradingDay(date time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	days, ok := c.days[date.Year()]
+	if !ok {
+		var err error
+		days, err = fetchSchedule(date.Year())
+		if err != nil {
+			// tried again with the next date
+			log.Println("holiday schedule", err)
+		} else {
+			c.days[date.Year()] = days
+		}
+	}
+	if open, ok := days[date.Format("2006-01-02")]; ok {
+		return open
+	}
+	return date.Weekday() != time.Saturday && date.Weekday() != time.Sunday
+}
+
+func fetchSchedule(year int) (map[string]bool, error) {
+	var schedule struct {
+		Stat string
+		Data [][]string
+	}
+	contents, err := httpGet(fmt.Sprintf(urlTSEHolidays, year-1911))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(contents, &schedule); err != nil {
+		return nil, err
+	}
+	if schedule.Stat != "OK" {
+		return nil, fmt.Errorf("schedule of %d: %s", year, schedule.Stat)
+	}
+	return parseSchedule(schedule.Data), nil
+}
+
+// parseSchedule reads the rows of name, date, weekday and note.  Besides
+// the holidays the schedule lists the first and last trading days around
+// them and the Saturdays made up for them, which are trading days unless
+// only settled.
+func parseSchedule(data [][]string) map[string]bool {
+	days := make(map[string]bool)
+	for _, row := range data {
+		if len(row) < 2 {
+			continue
+		}
+		open := (strings.Contains(row[0], "交易") || strings.Contains(row[0], "上班")) && !strings.Contains(row[0], "無交易")
+		date, err := time.Parse("2006-01-02", row[1])
+		if err != nil {
+			// 107/01/01 of the older schedules
+			fields := strings.Split(row[1], "/")
+			if len(fields) != 3 {
+				continue
+			}
+			y, _ := strconv.Atoi(fields[0])
+			m, _ := strconv.Atoi(fields[1])
+			d, _ := strconv.Atoi(fields[2])
+			if y == 0 || m == 0 || d == 0 {
+				continue
+			}
+			date = time.Date(y+1911, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+		}
+		days[date.Format("2006-01-02")] = open
+	}
+	return days
+}
+
+// scheduleDataset crawls the trading days missed before the start, then
+// waits for the publication time of every trading day and polls until the
+// trade date appears or the window closes.
+func scheduleDataset(store Store, d dataset, local *time.Location, cal *calendar, mu *sync.Mutex, clk clock) {
+	now := clk.Now().In(local)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, local)
+	catchUp(store, d, today, cal, mu, clk)
+
+	date := today
+	for {
+		date = nextTradeDate(d, date, clk.Now().In(local), cal)
+		publish := date.Add(d.published)
+		if wait := publish.Sub(clk.Now()); wait > 0 {
+			log.Println(d.name, "waits until", publish.Format("2006-01-02 15:04"))
+			clk.Sleep(wait)
+		}
+		pollDate(store, d, date, publish.Add(pollWindow), mu, clk)
+		date = date.AddDate(0, 0, 1)
+	}
+}
+
+// nextTradeDate returns the first trading day from date whose window is
+// still open at now.
+func nextTradeDate(d dataset, date, now time.Time, cal *calendar) time.Time {
+	for ; ; date = date.AddDate(0, 0, 1) {
+		if now.After(date.Add(d.published).Add(pollWindow)) {
+			continue
+		}
+		if !cal.tradingDay(date) {
+			log.Println(d.name, date.Format("2006-01-02"), "is not a trading day")
+			continue
+		}
+		return date
+	}
+}
+
+// catchUp crawls every trading day of the last maxCatchUpDays which is not
+// stored and whose window closed before the daemon started, once each.
+func catchUp(store Store, d dataset, today time.Time, cal *calendar, mu *sync.Mutex, clk clock) {
+	now := clk.Now()
+	from := today.AddDate(0, 0, 1-maxCatchUpDays)
+	stored, err := storedDates(store, d, from, today)
+	if err != nil {
+		log.Println(d.name, err)
+		return
+	}
+	for date := from; !date.After(today); date = date.AddDate(0, 0, 1) {
+		if !now.After(date.Add(d.published).Add(pollWindow)) || stored(date) || !cal.tradingDay(date) {
+			continue
+		}
+		pollDate(store, d, date, now, mu, clk)
+	}
+}
+
+// storedDates tells the dates of d between from and to which are stored,
+// the file stores only know their last date.
+func storedDates(store Store, d dataset, from, to time.Time) (func(time.Time) bool, error) {
+	if ss, ok := store.(statusStore); ok {
+		dates, err := ss.tradeDates(d.table, from, to)
+		if err != nil {
+			return nil, err
+		}
+		return func(date time.Time) bool { return dates[date.Format("2006-01-02")] }, nil
+	}
+	// 0 when empty
+	last, _ := store.LastTradeDate(d.table)
+	return func(date time.Time) bool {
+		return date.Year()*10000+int(date.Month())*100+date.Day() <= last
+	}, nil
+}
+
+func pollDate(store Store, d dataset, date, deadline time.Time, mu *sync.Mutex, clk clock) {
+	run := &crawlRun{dataset: d.name, tradeDate: date, startedAt: clk.Now()}
+	delay := retryDelay
+	for {
+		run.attempts++
+		mu.Lock()
+		log.Println(d.name, date.Format("2006-01-02"), "attempt", run.attempts)
+		count, err := d.crawl(store, date)
+		mu.Unlock()
+		if err == nil {
+			log.Println(d.name, count, "rows")
+			run.status = "ok"
+			run.rowCount = count
+			run.err = ""
+			break
+		}
+		if err == errNoData {
+			run.status = "no_data"
+		} else {
+			log.Println(d.name, err)
+			run.status = "error"
+			run.err = err.Error()
+		}
+		if clk.Now().Add(delay).After(deadline) {
+			break
+		}
+		clk.Sleep(delay)
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+	run.finishedAt = clk.Now()
+	log.Println(d.name, date.Format("2006-01-02"), run.status, "after", run.attempts, "attempts")
+
+	if recorder, ok := store.(runRecorder); ok {
+		if err := recorder.recordRun(run); err != nil {
+			log.Println("crawl_runs", err)
+		}
+	}
+}
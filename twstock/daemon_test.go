@@ -0,0 +1,143 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	data := [][]string{
+		{"中華民國開國紀念日", "2018-01-01", "一", "依規定放假1日。"},
+		{"國曆新年開始交易日", "2018-01-02", "二", "國曆新年開始交易。"},
+		{"市場無交易，僅辦理結算交割作業。", "2018-02-13", "二", ""},
+		{"農曆春節前最後交易日", "2018-02-12", "一", ""},
+		{"和平紀念日", "107/02/28", "三", "依規定放假1日。"},
+		{"補行上班日", "2018-03-31", "六", "補行交易。"},
+		{"bad date", "2018", "", ""},
+		{"short"},
+	}
+	want := map[string]bool{
+		"2018-01-01": false,
+		"2018-01-02": true,
+		"2018-02-12": true,
+		"2018-02-13": false,
+		"2018-02-28": false,
+		"2018-03-31": true,
+	}
+	if got := parseSchedule(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSchedule = %v, want %v", got, want)
+	}
+}
+
+// fakeClock moves on when slept on.
+type fakeClock struct {
+	now   time.Time
+	slept []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.slept = append(c.slept, d)
+	c.now = c.now.Add(d)
+}
+
+// fakeCrawl returns errNoData for the first noData attempts of a date and
+// records the dates tried.
+type fakeCrawl struct {
+	noData int
+	tries  map[string]int
+	dates  []string
+}
+
+func (f *fakeCrawl) crawl(store Store, date time.Time) (int, error) {
+	day := date.Format("2006-01-02")
+	f.dates = append(f.dates, day)
+	f.tries[day]++
+	if f.tries[day] <= f.noData {
+		return 0, errNoData
+	}
+	return 100, nil
+}
+
+func testCalendar() *calendar {
+	// 2018-06-29 a holiday, 2018-06-30 a Saturday made up for it
+	return &calendar{days: map[int]map[string]bool{2018: {"2018-06-29": false, "2018-06-30": true}}}
+}
+
+func TestPollDateBackoff(t *testing.T) {
+	local := time.FixedZone("Asia/Taipei", 8*60*60)
+	date := time.Date(2018, 7, 2, 0, 0, 0, 0, local)
+	publish := date.Add(14 * time.Hour)
+	tests := []struct {
+		name     string
+		noData   int
+		deadline time.Time
+		slept    []time.Duration
+		tries    int
+	}{
+		{"found", 3, publish.Add(pollWindow), []time.Duration{5 * time.Minute, 10 * time.Minute, 20 * time.Minute}, 4},
+		{"capped", 6, publish.Add(pollWindow), []time.Duration{5 * time.Minute, 10 * time.Minute, 20 * time.Minute, 30 * time.Minute, 30 * time.Minute, 30 * time.Minute}, 7},
+		// 5+10+20 minutes, then 30 more would pass the deadline
+		{"deadline", 100, publish.Add(time.Hour), []time.Duration{5 * time.Minute, 10 * time.Minute, 20 * time.Minute}, 4},
+		{"once", 100, publish, nil, 1},
+	}
+	for _, tt := range tests {
+		clk := &fakeClock{now: publish}
+		f := &fakeCrawl{noData: tt.noData, tries: map[string]int{}}
+		d := dataset{name: "quotes", table: "daily_quotes", crawl: f.crawl, published: 14 * time.Hour}
+		var mu sync.Mutex
+		pollDate(&statusOnly{&fakeStatusStore{}}, d, date, tt.deadline, &mu, clk)
+		if !reflect.DeepEqual(clk.slept, tt.slept) {
+			t.Errorf("%s: slept %v, want %v", tt.name, clk.slept, tt.slept)
+		}
+		if got := f.tries["2018-07-02"]; got != tt.tries {
+			t.Errorf("%s: %d attempts, want %d", tt.name, got, tt.tries)
+		}
+	}
+}
+
+func TestCatchUp(t *testing.T) {
+	local := time.FixedZone("Asia/Taipei", 8*60*60)
+	// Wednesday before the quotes of the day are published
+	clk := &fakeClock{now: time.Date(2018, 7, 4, 10, 0, 0, 0, local)}
+	today := time.Date(2018, 7, 4, 0, 0, 0, 0, local)
+	f := &fakeCrawl{noData: 1, tries: map[string]int{}}
+	d := dataset{name: "quotes", table: "daily_quotes", crawl: f.crawl, published: 14 * time.Hour}
+	store := &statusOnly{&fakeStatusStore{dates: map[string]bool{"2018-06-28": true}}}
+
+	var mu sync.Mutex
+	catchUp(store, d, today, testCalendar(), &mu, clk)
+	// every missed trading day once, even without data
+	want := []string{"2018-06-30", "2018-07-02", "2018-07-03"}
+	if !reflect.DeepEqual(f.dates, want) {
+		t.Errorf("crawled %v, want %v", f.dates, want)
+	}
+	if len(clk.slept) > 0 {
+		t.Errorf("slept %v", clk.slept)
+	}
+}
+
+func TestNextTradeDate(t *testing.T) {
+	local := time.FixedZone("Asia/Taipei", 8*60*60)
+	d := dataset{name: "quotes", published: 14 * time.Hour}
+	cal := testCalendar()
+	tests := []struct {
+		date, now, want string
+	}{
+		{"2018-07-02", "2018-07-02 10:00", "2018-07-02"},
+		{"2018-07-02", "2018-07-02 19:59", "2018-07-02"},
+		{"2018-07-02", "2018-07-02 20:01", "2018-07-03"}, // window closed
+		{"2018-06-29", "2018-06-29 10:00", "2018-06-30"}, // holiday, then made up on Saturday
+		{"2018-07-06", "2018-07-06 21:00", "2018-07-09"}, // weekend
+	}
+	for _, tt := range tests {
+		date, _ := time.ParseInLocation("2006-01-02", tt.date, local)
+		now, _ := time.ParseInLocation("2006-01-02 15:04", tt.now, local)
+		if got := nextTradeDate(d, date, now, cal).Format("2006-01-02"); got != tt.want {
+			t.Errorf("nextTradeDate(%s, %s) = %s, want %s", tt.date, tt.now, got, tt.want)
+		}
+	}
+}
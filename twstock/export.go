@@ -0,0 +1,382 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// tables which can be exported, and the column filtered by -s
+var exportTables = map[string]string{
+	"daily_quotes":       "security_code",
+	"daily_investors":    "security_code",
+	"daily_margin_short": "security_code",
+	"daily_indices":      "security_code",
+	"weekly_quotes":      "security_code",
+	"monthly_quotes":     "security_code",
+	"index_values":       "index_code",
+	"index_investors":    "index_code",
+	"index_margin_short": "index_code",
+}
+
+const defaultExportTables = "daily_quotes,daily_investors,daily_margin_short,daily_indices,index_values,index_investors,index_margin_short"
+
+// Reader is implemented by the database stores, export and serve read the
+// stored tables through it.
+type Reader interface {
+	ReadRows(table string, q rowQuery) (*sql.Rows, error)
+}
+
+// rowQuery selects the rows of a table between from and to, ordered by
+// trade date and code.
+type rowQuery struct {
+	from, to time.Time
+	codes    []string // every code when empty
+	// columns are read cast to text, so SQLite numbers keep their decimal
+	// text rather than the float64 formatting, every column as stored when
+	// empty
+	columns []string
+	// a page of limit rows from offset, every row when limit is 0
+	limit, offset int
+}
+
+// where returns the condition of q and its arguments.
+func (s *sqlStore) where(codeColumn string, q rowQuery) (string, []interface{}) {
+	args := []interface{}{q.from.Format("2006-01-02"), q.to.Format("2006-01-02")}
+	where := " WHERE trade_date BETWEEN " + s.placeholder(1) + " AND " + s.placeholder(2)
+	if len(q.codes) > 0 {
+		var placeholders []string
+		for _, code := range q.codes {
+			args = append(args, code)
+			placeholders = append(placeholders, s.placeholder(len(args)))
+		}
+		where += " AND " + codeColumn + " IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+	return where, args
+}
+
+func (s *sqlStore) ReadRows(table string, q rowQuery) (*sql.Rows, error) {
+	codeColumn, ok := exportTables[table]
+	if !ok {
+		return nil, fmt.Errorf("unknown table %q", table)
+	}
+	selected := "*"
+	if len(q.columns) > 0 {
+		casts := make([]string, len(q.columns))
+		for i, column := range q.columns {
+			casts[i] = "CAST(" + column + " AS text) AS " + column
+		}
+		selected = strings.Join(casts, ", ")
+	}
+	where, args := s.where(codeColumn, q)
+	query := "SELECT " + selected + " FROM " + table + where + " ORDER BY trade_date, " + codeColumn
+	if q.limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", q.limit, q.offset)
+	}
+	return s.db.Query(query, args...)
+}
+
+func init() {
+	addCommand("export", "write stored tables to parquet or csv files", runExport)
+}
+
+func runExport(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	flagStore := flags.String("db", "postgres", "store: postgres or sqlite")
+	flagDSN := flags.String("dsn", "", "data source name (default: the shared database, or "+defaultSQLiteFile+" for sqlite)")
+	flagTables := flags.String("tables", defaultExportTables, "tables separated by comma")
+	flagCodes := flags.String("s", "", "security or index codes separated by comma (default: all)")
+	flagFromDate := flags.Int("f", 0, "from date YYYYMMDD (default: January 1st of this year)")
+	flagToDate := flags.Int("t", 0, "to date YYYYMMDD (default: today)")
+	flagFormat := flags.String("format", "parquet", "parquet (partitioned by year/month) or csv")
+	flagOutput := flags.String("o", "export", "output directory")
+	flags.Parse(args)
+
+	if *flagFormat != "parquet" && *flagFormat != "csv" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	store, err := openStore(*flagStore, *flagDSN)
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	defer store.Close()
+	reader, ok := store.(Reader)
+	if !ok {
+		log.Println(*flagStore, "can not be exported")
+		os.Exit(1)
+	}
+
+	today := time.Now()
+	fromDate := *flagFromDate
+	toDate := *flagToDate
+	if fromDate == 0 || fromDate < kMinDate {
+		fromDate = today.Year()*10000 + 101
+	}
+	if toDate == 0 || toDate < kMinDate {
+		toDate = today.Year()*10000 + int(today.Month())*100 + today.Day()
+	}
+	from := time.Date(fromDate/10000, time.Month(fromDate%10000/100), fromDate%100, 0, 0, 0, 0, time.UTC)
+	to := time.Date(toDate/10000, time.Month(toDate%10000/100), toDate%100, 0, 0, 0, 0, time.UTC)
+
+	var codes []string
+	for _, code := range strings.Split(*flagCodes, ",") {
+		if code = strings.TrimSpace(code); len(code) > 0 {
+			codes = append(codes, code)
+		}
+	}
+
+	for _, table := range strings.Split(*flagTables, ",") {
+		table = strings.TrimSpace(table)
+		log.Println("export", table, fromDate, toDate)
+		count, err := exportTable(reader, table, from, to, codes, *flagFormat, *flagOutput)
+		if err != nil {
+			log.Println(table, err)
+			continue
+		}
+		log.Println(table, count, "rows")
+	}
+}
+
+// column types follow the migrations: trade_date is a DATE, numeric columns
+// are DOUBLE and everything else is UTF8.
+const (
+	typeDate = iota
+	typeNumber
+	typeString
+)
+
+func columnTypes(rows *sql.Rows) ([]string, []int, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, nil, err
+	}
+	kinds := make([]int, len(columns))
+	for i, column := range columns {
+		name := strings.ToUpper(types[i].DatabaseTypeName())
+		switch {
+		case column == "trade_date" || name == "DATE":
+			kinds[i] = typeDate
+		case name == "NUMERIC" || name == "DECIMAL" || strings.HasPrefix(name, "INT") || strings.HasPrefix(name, "FLOAT"):
+			kinds[i] = typeNumber
+		default:
+			kinds[i] = typeString
+		}
+	}
+	return columns, kinds, nil
+}
+
+// tableWriter is one output file, a parquet partition or the csv file.
+type tableWriter interface {
+	Write(values []sql.NullString) error
+	Close() error
+}
+
+func exportTable(reader Reader, table string, from, to time.Time, codes []string, format, output string) (int, error) {
+	rows, err := reader.ReadRows(table, rowQuery{from: from, to: to, codes: codes})
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, kinds, err := columnTypes(rows)
+	if err != nil {
+		return 0, err
+	}
+	dateIndex := -1
+	for i, column := range columns {
+		if column == "trade_date" {
+			dateIndex = i
+		}
+	}
+
+	values := make([]sql.NullString, len(columns))
+	scanArgs := make([]interface{}, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+
+	var w tableWriter
+	partition := ""
+	count := 0
+	for rows.Next() {
+		if err := rows.Scan(scanArgs...); err != nil {
+			return count, err
+		}
+		// postgres returns dates as RFC3339, keep YYYY-MM-DD
+		if dateIndex >= 0 && len(values[dateIndex].String) > 10 {
+			values[dateIndex].String = values[dateIndex].String[:10]
+		}
+
+		if format == "csv" {
+			if w == nil {
+				w, err = newCSVTableWriter(filepath.Join(output, table+".csv"), columns)
+			}
+		} else if dateIndex >= 0 && len(values[dateIndex].String) == 10 {
+			// year=2018/month=06, the hive layout read by pandas and DuckDB
+			p := "year=" + values[dateIndex].String[:4] + "/month=" + values[dateIndex].String[5:7]
+			if p != partition {
+				if w != nil {
+					if err := w.Close(); err != nil {
+						return count, err
+					}
+				}
+				partition = p
+				w, err = newParquetTableWriter(filepath.Join(output, table, p, table+".parquet"), columns, kinds)
+			}
+		}
+		if err != nil {
+			return count, err
+		}
+		if w == nil {
+			continue
+		}
+		if err := w.Write(values); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+	if w != nil {
+		return count, w.Close()
+	}
+	return count, nil
+}
+
+type csvTableWriter struct {
+	file   *os.File
+	writer *csv.Writer
+	record []string
+}
+
+func newCSVTableWriter(path string, columns []string) (*csvTableWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &csvTableWriter{file, csv.NewWriter(file), make([]string, len(columns))}
+	if err := w.writer.Write(columns); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *csvTableWriter) Write(values []sql.NullString) error {
+	for i, v := range values {
+		w.record[i] = v.String
+	}
+	return w.writer.Write(w.record)
+}
+
+func (w *csvTableWriter) Close() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// parquetTableWriter writes a partition to path.tmp and renames it on Close,
+// so an export replaces the partitions it covers and a failed one leaves the
+// old file in place.
+type parquetTableWriter struct {
+	path   string
+	file   source.ParquetFile
+	writer *writer.CSVWriter
+	kinds  []int
+}
+
+func newParquetTableWriter(path string, columns []string, kinds []int) (*parquetTableWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	md := make([]string, len(columns))
+	for i, column := range columns {
+		switch kinds[i] {
+		case typeDate:
+			md[i] = "name=" + column + ", type=INT32, convertedtype=DATE, repetitiontype=OPTIONAL"
+		case typeNumber:
+			md[i] = "name=" + column + ", type=DOUBLE, repetitiontype=OPTIONAL"
+		default:
+			md[i] = "name=" + column + ", type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY, repetitiontype=OPTIONAL"
+		}
+	}
+	file, err := local.NewLocalFileWriter(path + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	pw, err := writer.NewCSVWriter(md, file, 4)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &parquetTableWriter{path, file, pw, kinds}, nil
+}
+
+var epoch = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Write converts a row to a new record, the writer keeps the records of a
+// row group until it is flushed.
+func (w *parquetTableWriter) Write(values []sql.NullString) error {
+	record := make([]interface{}, len(values))
+	for i, v := range values {
+		if !v.Valid {
+			continue
+		}
+		switch w.kinds[i] {
+		case typeDate:
+			date, err := time.Parse("2006-01-02", v.String)
+			if err != nil {
+				return err
+			}
+			// days since 1970-01-01
+			record[i] = int32(date.Sub(epoch).Hours() / 24)
+		case typeNumber:
+			n, err := strconv.ParseFloat(v.String, 64)
+			if err != nil {
+				return err
+			}
+			record[i] = n
+		default:
+			record[i] = v.String
+		}
+	}
+	return w.writer.Write(record)
+}
+
+func (w *parquetTableWriter) Close() error {
+	if err := w.writer.WriteStop(); err != nil {
+		w.file.Close()
+		os.Remove(w.path + ".tmp")
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		os.Remove(w.path + ".tmp")
+		return err
+	}
+	return os.Rename(w.path+".tmp", w.path)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+func TestParquetRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daily_quotes.parquet")
+	columns := []string{"trade_date", "security_code", "close_price"}
+	kinds := []int{typeDate, typeString, typeNumber}
+	rows := [][]sql.NullString{
+		{{String: "2018-06-27", Valid: true}, {String: "2330", Valid: true}, {String: "225.5", Valid: true}},
+		{{String: "2018-06-28", Valid: true}, {String: "2317", Valid: true}, {}},
+		{{String: "2018-06-29", Valid: true}, {String: "6488", Valid: true}, {String: "300", Valid: true}},
+	}
+
+	w, err := newParquetTableWriter(path, columns, kinds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// exportTable scans every row into the same slice
+	values := make([]sql.NullString, len(columns))
+	for _, row := range rows {
+		copy(values, row)
+		if err := w.Write(values); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("%s.tmp left behind", path)
+	}
+
+	file, err := local.NewLocalFileReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	pr, err := reader.NewParquetColumnReader(file, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.ReadStop()
+	if n := pr.GetNumRows(); n != int64(len(rows)) {
+		t.Fatalf("%d rows, want %d", n, len(rows))
+	}
+
+	want := [][]interface{}{
+		// days since 1970-01-01
+		{int32(17709), int32(17710), int32(17711)},
+		{"2330", "2317", "6488"},
+		{225.5, nil, 300.0},
+	}
+	for i := range columns {
+		got, _, _, err := pr.ReadColumnByIndex(int64(i), int64(len(rows)))
+		if err != nil {
+			t.Fatal(columns[i], err)
+		}
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("%s = %v, want %v", columns[i], got, want[i])
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/transform"
+)
+
+var enc = traditionalchinese.Big5
+
+const (
+	urlTSEDailyQuote       = "http://www.twse.com.tw/exchangeReport/MI_INDEX?response=json&date=%4d%02d%02d&type=ALLBUT0999"
+	urlOTCDailyQuote       = "http://www.tpex.org.tw/web/stock/aftertrading/otc_quotes_no1430/stk_wn1430_download.php?l=zh-tw&d=%d/%02d/%02d&se=EW&s=0,asc,0"
+	urlTSEDailyInvestor    = "http://www.twse.com.tw/fund/T86?response=json&date=%4d%02d%02d&selectType=ALLBUT0999"
+	urlOTCDailyInvestor    = "http://www.tpex.org.tw/web/stock/3insti/daily_trade/3itrade_hedge_download.php?l=zh-tw&se=EW&t=D&d=%d/%02d/%02d&s=0,asc"
+	urlTSEDailyMarginShort = "http://www.twse.com.tw/exchangeReport/MI_MARGN?response=json&date=%4d%02d%02d&selectType=ALL"
+	kMinSize               = 1024
+)
+
+// errNoData is returned for holidays and days not published yet, the
+// fetchers return other errors when the exchange could not be read.
+var errNoData = errors.New("no data")
+
+func tseURL(format string, date time.Time) string {
+	return fmt.Sprintf(format, date.Year(), int(date.Month()), date.Day())
+}
+
+func otcURL(format string, date time.Time) string {
+	return fmt.Sprintf(format, date.Year()-1911, int(date.Month()), date.Day())
+}
+
+func httpGet(url string) ([]byte, error) {
+	log.Println(url)
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	log.Println("----")
+	log.Println(resp.StatusCode)
+	log.Println(resp.Status)
+	log.Println("Body len = ", len(contents))
+
+	return contents, nil
+}
+
+// fetchTSEJSON reads a TWSE report, data is the table of the report.
+// A short body is the message of a day without data.
+func fetchTSEJSON(url string, data interface{}) error {
+	contents, err := httpGet(url)
+	if err != nil {
+		return err
+	}
+	if len(contents) < kMinSize {
+		return errNoData
+	}
+	err = json.Unmarshal(contents, data)
+	if err != nil {
+		log.Println("json unmarshal: ", err)
+		return err
+	}
+	return nil
+}
+
+// fetchOTCCSV reads a Big5 TPEx download and skips the title lines.
+func fetchOTCCSV(url string, skip int) ([][]string, error) {
+	contents, err := httpGet(url)
+	if err != nil {
+		return nil, err
+	}
+
+	r := transform.NewReader(strings.NewReader(string(contents)), enc.NewDecoder())
+	input := bufio.NewScanner(r)
+
+	lineCount := 0
+	out := ""
+	for input.Scan() {
+		in := strings.TrimSpace(input.Text())
+		if len(in) <= 20 {
+			continue
+		}
+		lineCount++
+		if lineCount > skip {
+			out += in + "\n"
+		}
+	}
+	if lineCount < 2 {
+		return nil, errNoData
+	}
+
+	csvr := csv.NewReader(strings.NewReader(out))
+	csvr.FieldsPerRecord = -1
+	records, err := csvr.ReadAll()
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return records, nil
+}
+
+func number(s string) string {
+	s = strings.Replace(strings.TrimSpace(s), ",", "", -1)
+	if strings.Contains(s, "--") || len(s) == 0 {
+		return ""
+	}
+	return s
+}
+
+// numbers picks the columns of data, -1 is a missing column.
+func numbers(data []string, columns ...int) []string {
+	out := make([]string, len(columns))
+	for i, c := range columns {
+		if c >= 0 && c < len(data) {
+			out[i] = number(data[c])
+		}
+	}
+	return out
+}
+
+type tseTable struct {
+	Data   [][]string `json:"data"`
+	Quotes [][]string `json:"data5"`
+}
+
+func fetchTSEQuotes(date time.Time) ([]Quote, error) {
+	var table tseTable
+	if err := fetchTSEJSON(tseURL(urlTSEDailyQuote, date), &table); err != nil {
+		return nil, err
+	}
+
+	// "fields5":["證券代號","證券名稱","成交股數","成交筆數","成交金額","開盤價","最高價","最低價","收盤價","漲跌(+/-)","漲跌價差","最後揭示買價","最後揭示買量","最後揭示賣價","最後揭示賣量","本益比"]
+	var quotes []Quote
+	for _, data := range table.Quotes {
+		if len(data) < 15 {
+			continue
+		}
+		v := numbers(data, 2, 3, 4, 5, 6, 7, 8, 11, 12, 13, 14)
+		quotes = append(quotes, Quote{strings.TrimSpace(data[0]), v[0], v[1], v[2], v[3], v[4], v[5], v[6], v[7], v[8], v[9], v[10]})
+	}
+	if len(quotes) == 0 {
+		return nil, errNoData
+	}
+	return quotes, nil
+}
+
+// fetchOTCQuotes also returns the code and 發行股數 of every quote, TSE
+// shares come from MOPS/sharesoutstanding.go.
+func fetchOTCQuotes(date time.Time) ([]Quote, [][]string, error) {
+	records, err := fetchOTCCSV(otcURL(urlOTCDailyQuote, date), 4)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 代號,名稱,收盤 ,漲跌,開盤 ,最高 ,最低,成交股數  , 成交金額(元), 成交筆數 ,最後買價,最後賣價,發行股數 ,次日漲停價 ,次日跌停價
+	var quotes []Quote
+	var shares [][]string
+	for _, data := range records {
+		if len(data) < 12 {
+			continue
+		}
+		code := strings.TrimSpace(data[0])
+		v := numbers(data, 7, 9, 8, 4, 5, 6, 2, 10, -1, 11, -1)
+		quotes = append(quotes, Quote{code, v[0], v[1], v[2], v[3], v[4], v[5], v[6], v[7], v[8], v[9], v[10]})
+		if len(data) > 12 && len(number(data[12])) > 0 {
+			shares = append(shares, []string{code, number(data[12])})
+		}
+	}
+	if len(quotes) == 0 {
+		return nil, nil, errNoData
+	}
+	return quotes, shares, nil
+}
+
+func newInvestor(code string, v []string) Investor {
+	return Investor{code, v[0], v[1], v[2], v[3], v[4], v[5], v[6], v[7], v[8], v[9], v[10], v[11], v[12], v[13], v[14], v[15], v[16]}
+}
+
+func fetchTSEInvestors(date time.Time) ([]Investor, error) {
+	var table tseTable
+	if err := fetchTSEJSON(tseURL(urlTSEDailyInvestor, date), &table); err != nil {
+		return nil, err
+	}
+
+	var investors []Investor
+	for _, data := range table.Data {
+		var v []string
+		switch {
+		case len(data) >= 19:
+			// since 2017-12-18
+			// "fields":["證券代號","證券名稱","外陸資買進股數(不含外資自營商)","外陸資賣出股數(不含外資自營商)","外陸資買賣超股數(不含外資自營商)","外資自營商買進股數","外資自營商賣出股數","外資自營商買賣超股數","投信買進股數","投信賣出股數","投信買賣超股數","自營商買賣超股數","自營商買進股數(自行買賣)","自營商賣出股數(自行買賣)","自營商買賣超股數(自行買賣)","自營商買進股數(避險)","自營商賣出股數(避險)","自營商買賣超股數(避險)","三大法人買賣超股數"]
+			v = numbers(data, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18)
+		case len(data) >= 16:
+			// "fields":["證券代號","證券名稱","外資買進股數","外資賣出股數","外資買賣超股數","投信買進股數","投信賣出股數","投信買賣超股數","自營商買賣超股數","自營商買進股數(自行買賣)","自營商賣出股數(自行買賣)","自營商買賣超股數(自行買賣)","自營商買進股數(避險)","自營商賣出股數(避險)","自營商買賣超股數(避險)","三大法人買賣超股數"]
+			v = numbers(data, 2, 3, 4, -1, -1, -1, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15)
+			v[3], v[4], v[5] = "0", "0", "0"
+		default:
+			continue
+		}
+		investors = append(investors, newInvestor(strings.TrimSpace(data[0]), v))
+	}
+	if len(investors) == 0 {
+		return nil, errNoData
+	}
+	return investors, nil
+}
+
+func fetchOTCInvestors(date time.Time) ([]Investor, error) {
+	records, err := fetchOTCCSV(otcURL(urlOTCDailyInvestor, date), 2)
+	if err != nil {
+		return nil, err
+	}
+
+	var investors []Investor
+	for _, data := range records {
+		var v []string
+		switch {
+		case len(data) >= 24:
+			// since 2018-01-15
+			// 代號,名稱,外資及陸資(不含外資自營商)-買進股數,外資及陸資(不含外資自營商)-賣出股數,外資及陸資(不含外資自營商)-買賣超股數,外資自營商-買進股數,外資自營商-賣出股數,外資自營商-買賣超股數,外資及陸資-買進股數,外資及陸資-賣出股數,外資及陸資-買賣超股數,投信-買進股數,投信-賣出股數,投信-買賣超股數,自營商(自行買賣)-買進股數,自營商(自行買賣)-賣出股數,自營商(自行買賣)-買賣超股數,自營商(避險)-買進股數,自營商(避險)-賣出股數,自營商(避險)-買賣超股數,自營商-買進股數,自營商-賣出股數,自營商-買賣超股數,三大法人買賣超股數合計
+			v = numbers(data, 2, 3, 4, 5, 6, 7, 11, 12, 13, 22, 14, 15, 16, 17, 18, 19, 23)
+		case len(data) >= 16:
+			// 代號,名稱,外資及陸資買股數,外資及陸資賣股數,外資及陸資淨買股數,投信買進股數,投信賣股數,投信淨買股數,自營淨買股數,自營商(自行買賣)買股數,自營商(自行買賣)賣股數,自營商(自行買賣)淨買股數,自營商(避險)買股數,自營商(避險)賣股數,自營商(避險)淨買股數,三大法人買賣超股數
+			v = numbers(data, 2, 3, 4, -1, -1, -1, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15)
+			v[3], v[4], v[5] = "0", "0", "0"
+		default:
+			continue
+		}
+		investors = append(investors, newInvestor(strings.TrimSpace(data[0]), v))
+	}
+	if len(investors) == 0 {
+		return nil, errNoData
+	}
+	return investors, nil
+}
+
+func fetchTSEMarginShort(date time.Time) ([]MarginShort, error) {
+	var table tseTable
+	if err := fetchTSEJSON(tseURL(urlTSEDailyMarginShort, date), &table); err != nil {
+		return nil, err
+	}
+
+	// "fields":["股票代號","股票名稱","買進","賣出","現金償還","前日餘額","今日餘額","限額","買進","賣出","現金償還","前日餘額","今日餘額","限額","資券互抵","註記"]
+	var margins []MarginShort
+	for _, data := range table.Data {
+		if len(data) < 15 {
+			continue
+		}
+		v := numbers(data, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14)
+		margins = append(margins, MarginShort{strings.TrimSpace(data[0]), v[0], v[1], v[2], v[3], v[4], v[5], v[6], v[7], v[8], v[9], v[10], v[11], v[12]})
+	}
+	if len(margins) == 0 {
+		return nil, errNoData
+	}
+	return margins, nil
+}
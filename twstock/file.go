@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultDataDir = "data"
+
+// fileStore writes every trade date to its own file,
+//
+//	data/<dataset>/<yyyy>/<yyyymmdd>.jsonl
+//
+// so crawling needs no database, import loads the files later.  The OTC
+// shares of the quotes go to data/shares_outstanding.
+type fileStore struct {
+	dir    string
+	format string // jsonl / csv
+}
+
+func openFileStore(dir, format string) (Store, error) {
+	if len(dir) == 0 {
+		dir = defaultDataDir
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &fileStore{dir, format}, nil
+}
+
+// datasetName returns the directory of a table.
+func datasetName(table string) string {
+	for _, d := range datasets {
+		if d.table == table {
+			return d.name
+		}
+	}
+	return table
+}
+
+func (s *fileStore) path(table string, date time.Time) string {
+	return filepath.Join(s.dir, datasetName(table), date.Format("2006"), date.Format("20060102")+"."+s.format)
+}
+
+// files returns the files of a table sorted by date.
+func (s *fileStore) files(table string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, datasetName(table), "[0-9][0-9][0-9][0-9]", "*."+s.format))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// fileDate returns YYYYMMDD of data/quotes/2018/20180629.jsonl
+func fileDate(path string) (int, error) {
+	name := filepath.Base(path)
+	return strconv.Atoi(strings.TrimSuffix(name, filepath.Ext(name)))
+}
+
+func (s *fileStore) LastTradeDate(table string) (int, error) {
+	files, err := s.files(table)
+	if err != nil {
+		return 0, err
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		if date, err := fileDate(files[i]); err == nil {
+			return date, nil
+		}
+	}
+	return 0, fmt.Errorf("%s is empty", table)
+}
+
+func (s *fileStore) WriteQuotes(date time.Time, quotes []Quote) error {
+	return s.replaceRows("daily_quotes", date, quoteColumns, quoteRows(quotes))
+}
+
+func (s *fileStore) WriteInvestors(date time.Time, investors []Investor) error {
+	return s.replaceRows("daily_investors", date, investorColumns, investorRows(investors))
+}
+
+func (s *fileStore) WriteMarginShort(date time.Time, margins []MarginShort) error {
+	return s.replaceRows("daily_margin_short", date, marginShortColumns, marginShortRows(margins))
+}
+
+// writeShares keeps the OTC shares of the quotes next to them, import
+// loads them with the quotes.
+func (s *fileStore) writeShares(date time.Time, shares [][]string) error {
+	return s.replaceRows("shares_outstanding", date, sharesColumns, shares)
+}
+
+func (s *fileStore) Close() error {
+	return nil
+}
+
+// replaceRows writes a temporary file and renames it, a crawl stopped
+// halfway never leaves a partial day.
+func (s *fileStore) replaceRows(table string, date time.Time, columns []string, rows [][]string) error {
+	path := s.path(table, date)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	tradeDate := date.Format("2006-01-02")
+	if s.format == "csv" {
+		err = writeCSVRows(w, tradeDate, columns, rows)
+	} else {
+		err = writeJSONRows(w, tradeDate, columns, rows)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(path + ".tmp")
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func writeCSVRows(w io.Writer, tradeDate string, columns []string, rows [][]string) error {
+	csvw := csv.NewWriter(w)
+	csvw.Write(append([]string{"trade_date"}, columns...))
+	for _, row := range rows {
+		csvw.Write(append([]string{tradeDate}, row...))
+	}
+	csvw.Flush()
+	return csvw.Error()
+}
+
+// writeJSONRows writes one object per line in the column order, numbers
+// are JSON numbers and empty values are null.
+func writeJSONRows(w io.Writer, tradeDate string, columns []string, rows [][]string) error {
+	for _, row := range rows {
+		line := `{"trade_date":"` + tradeDate + `"`
+		for i, value := range row {
+			line += "," + strconv.Quote(columns[i]) + ":"
+			if len(value) == 0 {
+				line += "null"
+			} else if _, err := strconv.ParseFloat(value, 64); err == nil && i > 0 {
+				line += value
+			} else {
+				b, _ := json.Marshal(value)
+				line += string(b)
+			}
+		}
+		if _, err := io.WriteString(w, line+"}\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRows reads a file written by replaceRows back to rows of columns.
+func readRows(path, format string, columns []string) ([][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rows [][]string
+	if format == "csv" {
+		csvr := csv.NewReader(file)
+		records, err := csvr.ReadAll()
+		if err != nil || len(records) == 0 {
+			return nil, err
+		}
+		index := make(map[string]int)
+		for i, name := range records[0] {
+			index[name] = i
+		}
+		for _, record := range records[1:] {
+			row := make([]string, len(columns))
+			for i, column := range columns {
+				if j, ok := index[column]; ok && j < len(record) {
+					row[i] = record[j]
+				}
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	}
+
+	dec := json.NewDecoder(file)
+	dec.UseNumber()
+	for {
+		var record map[string]interface{}
+		if err := dec.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			if value, ok := record[column]; ok && value != nil {
+				row[i] = fmt.Sprint(value)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// The OTC shares of a file crawl have to reach the database with import.
+func TestImportShares(t *testing.T) {
+	dir := t.TempDir()
+	date := time.Date(2018, 6, 29, 0, 0, 0, 0, time.UTC)
+	for _, format := range []string{"jsonl", "csv"} {
+		files := &fileStore{filepath.Join(dir, format), format}
+		if err := files.writeShares(date, [][]string{{"6488", "180000000"}, {"3105", "425000000"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		store, err := openSQLite(filepath.Join(dir, format+".db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+		if err := importShares(store, files, date); err != nil {
+			t.Fatal(err)
+		}
+		// a date without shares is skipped
+		if err := importShares(store, files, date.AddDate(0, 0, -1)); err != nil {
+			t.Fatal(err)
+		}
+
+		var shares string
+		err = store.(*sqlStore).db.QueryRow("SELECT CAST(shares AS text) FROM shares_outstanding WHERE trade_date = '2018-06-29' AND security_code = '3105'").Scan(&shares)
+		if err != nil {
+			t.Fatal(format, err)
+		}
+		if shares != "425000000" {
+			t.Errorf("%s: shares %s, want 425000000", format, shares)
+		}
+	}
+}
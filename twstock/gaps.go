@@ -0,0 +1,277 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// marketRows is what one market returned for a crawl, err is errNoData
+// when the exchange has nothing for the date.
+type marketRows struct {
+	market string
+	err    error
+	rows   [][]string
+}
+
+// statusStore is implemented by the database stores, crawl_status is kept
+// next to the data.
+type statusStore interface {
+	writeStatus(dataset, market string, date time.Time, status string, rowCount int, checksum string) error
+	// tradeDates returns the YYYY-MM-DD dates of table between from and to.
+	tradeDates(table string, from, to time.Time) (map[string]bool, error)
+	// crawlStatus returns the statuses by date of a dataset, one per market.
+	crawlStatus(dataset string, from, to time.Time) (map[string][]string, error)
+}
+
+func rowsChecksum(rows [][]string) string {
+	h := sha256.New()
+	for _, row := range rows {
+		h.Write([]byte(strings.Join(row, "\t") + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fetchError returns the first error of the markets, errNoData only when
+// none failed otherwise.
+func fetchError(markets []marketRows) error {
+	var err error
+	for _, m := range markets {
+		if m.err != nil && (err == nil || err == errNoData) {
+			err = m.err
+		}
+	}
+	return err
+}
+
+// recordStatus writes crawl_status of every market after a crawl, err is
+// the result of the write, nil when a fetch failed and nothing was written.
+func recordStatus(store Store, dataset string, date time.Time, markets []marketRows, err error) {
+	ss, ok := store.(statusStore)
+	if !ok {
+		return
+	}
+	incomplete := fetchError(markets) != nil
+	for _, m := range markets {
+		status := "ok"
+		switch {
+		case m.err == errNoData:
+			status = "no_data"
+		case m.err != nil:
+			status = "error"
+		case incomplete:
+			status = "incomplete"
+		case err != nil:
+			status = "error"
+		}
+		checksum := ""
+		if status == "ok" {
+			checksum = rowsChecksum(m.rows)
+		}
+		if err := ss.writeStatus(dataset, m.market, date, status, len(m.rows), checksum); err != nil {
+			log.Println("crawl_status", err)
+		}
+	}
+}
+
+func (s *sqlStore) writeStatus(dataset, market string, date time.Time, status string, rowCount int, checksum string) error {
+	placeholders := make([]string, 7)
+	for i := range placeholders {
+		placeholders[i] = s.placeholder(i + 1)
+	}
+	_, err := s.db.Exec("INSERT INTO crawl_status (dataset, market, trade_date, status, row_count, fetched_at, checksum) VALUES ("+strings.Join(placeholders, ", ")+")"+
+		" ON CONFLICT (dataset, market, trade_date) DO UPDATE SET status = excluded.status, row_count = excluded.row_count, fetched_at = excluded.fetched_at, checksum = excluded.checksum",
+		dataset, market, date.Format("2006-01-02"), status, rowCount, time.Now(), checksum)
+	return err
+}
+
+func (s *sqlStore) tradeDates(table string, from, to time.Time) (map[string]bool, error) {
+	rows, err := s.db.Query("SELECT DISTINCT trade_date FROM "+table+" WHERE trade_date BETWEEN "+s.placeholder(1)+" AND "+s.placeholder(2),
+		from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	dates := make(map[string]bool)
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return nil, err
+		}
+		// postgres returns dates as RFC3339
+		if len(date) > 10 {
+			date = date[:10]
+		}
+		dates[date] = true
+	}
+	return dates, rows.Err()
+}
+
+func (s *sqlStore) crawlStatus(dataset string, from, to time.Time) (map[string][]string, error) {
+	rows, err := s.db.Query("SELECT trade_date, market, status FROM crawl_status WHERE dataset = "+s.placeholder(1)+" AND trade_date BETWEEN "+s.placeholder(2)+" AND "+s.placeholder(3)+" ORDER BY market",
+		dataset, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	statuses := make(map[string][]string)
+	for rows.Next() {
+		var date, market, status string
+		if err := rows.Scan(&date, &market, &status); err != nil {
+			return nil, err
+		}
+		if len(date) > 10 {
+			date = date[:10]
+		}
+		statuses[date] = append(statuses[date], market+":"+status)
+	}
+	return statuses, rows.Err()
+}
+
+func init() {
+	addCommand("gaps", "list, and refetch with -fetch, trading days missing in the stored datasets", runGaps)
+}
+
+// gap is a date missing in a dataset.
+type gap struct {
+	date   string
+	reason string
+}
+
+func runGaps(args []string) {
+	flags := flag.NewFlagSet("gaps", flag.ExitOnError)
+	flagStore := flags.String("db", "postgres", "store: postgres or sqlite")
+	flagDSN := flags.String("dsn", "", "data source name (default: the shared database, or "+defaultSQLiteFile+" for sqlite)")
+	flagDatasets := flags.String("d", "quotes,investors,margin", "datasets separated by comma")
+	flagFromDate := flags.Int("f", 0, "from date YYYYMMDD (default: first trade date of the dataset)")
+	flagToDate := flags.Int("t", 0, "to date YYYYMMDD (default: yesterday)")
+	flagFetch := flags.Bool("fetch", false, "crawl the missing days again")
+	flags.Parse(args)
+
+	local, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		panic(err)
+	}
+
+	store, err := openStore(*flagStore, *flagDSN)
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	defer store.Close()
+	ss, ok := store.(statusStore)
+	if !ok {
+		log.Println(*flagStore, "has no crawl_status")
+		os.Exit(1)
+	}
+
+	from := time.Date(kMinDate/10000, 1, 1, 0, 0, 0, 0, local)
+	if *flagFromDate > kMinDate {
+		from = time.Date(*flagFromDate/10000, time.Month(*flagFromDate%10000/100), *flagFromDate%100, 0, 0, 0, 0, local)
+	}
+	now := time.Now().In(local)
+	to := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, local)
+	if *flagToDate > kMinDate {
+		to = time.Date(*flagToDate/10000, time.Month(*flagToDate%10000/100), *flagToDate%100, 0, 0, 0, 0, local)
+	}
+
+	// a date any dataset has is a trading day
+	traded := make(map[string]bool)
+	for _, d := range datasets {
+		dates, err := ss.tradeDates(d.table, from, to)
+		if err != nil {
+			log.Println(d.table, err)
+			os.Exit(1)
+		}
+		for date := range dates {
+			traded[date] = true
+		}
+	}
+
+	for _, d := range selectDatasets(*flagDatasets) {
+		gaps, err := findGaps(ss, d, from, to, *flagFromDate > kMinDate, traded)
+		if err != nil {
+			log.Println(d.name, err)
+			continue
+		}
+		for _, g := range gaps {
+			fmt.Println(d.name, g.date, g.reason)
+		}
+		if !*flagFetch {
+			continue
+		}
+		for _, g := range gaps {
+			date, _ := time.ParseInLocation("2006-01-02", g.date, local)
+			log.Println(d.name, g.date)
+			time.Sleep(1 * time.Second)
+			count, err := d.crawl(store, date)
+			if err != nil && err != errNoData {
+				log.Println(d.name, err)
+			} else if err == errNoData {
+				log.Println(d.name, "no data")
+			} else {
+				log.Println(d.name, count, "rows")
+			}
+		}
+	}
+}
+
+// findGaps returns the dates between from and to without rows of d, which
+// either another dataset has, or are weekdays crawl_status does not know as
+// no_data in every market.  Without fromSet the check starts at the first date of d.
+func findGaps(ss statusStore, d dataset, from, to time.Time, fromSet bool, traded map[string]bool) ([]gap, error) {
+	dates, err := ss.tradeDates(d.table, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if !fromSet {
+		if len(dates) == 0 {
+			return nil, nil
+		}
+		var sorted []string
+		for date := range dates {
+			sorted = append(sorted, date)
+		}
+		sort.Strings(sorted)
+		first, err := time.ParseInLocation("2006-01-02", sorted[0], from.Location())
+		if err != nil {
+			return nil, err
+		}
+		from = first
+	}
+	statuses, err := ss.crawlStatus(d.name, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var gaps []gap
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		day := date.Format("2006-01-02")
+		if dates[day] {
+			continue
+		}
+		status := strings.Join(statuses[day], " ")
+		holiday := len(statuses[day]) > 0
+		for _, s := range statuses[day] {
+			if !strings.HasSuffix(s, ":no_data") {
+				holiday = false
+			}
+		}
+		switch {
+		case traded[day]:
+			gaps = append(gaps, gap{day, strings.TrimSpace("missing " + status)})
+		case date.Weekday() == time.Saturday || date.Weekday() == time.Sunday:
+		case len(status) == 0:
+			gaps = append(gaps, gap{day, "never crawled"})
+		case !holiday:
+			gaps = append(gaps, gap{day, status})
+		}
+	}
+	return gaps, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeStatusStore holds the trade dates and crawl_status of one dataset.
+type fakeStatusStore struct {
+	dates    map[string]bool
+	statuses map[string][]string
+}
+
+func (f *fakeStatusStore) writeStatus(dataset, market string, date time.Time, status string, rowCount int, checksum string) error {
+	day := date.Format("2006-01-02")
+	f.statuses[day] = append(f.statuses[day], market+":"+status)
+	return nil
+}
+
+func (f *fakeStatusStore) tradeDates(table string, from, to time.Time) (map[string]bool, error) {
+	dates := make(map[string]bool)
+	for date := range f.dates {
+		if date >= from.Format("2006-01-02") && date <= to.Format("2006-01-02") {
+			dates[date] = true
+		}
+	}
+	return dates, nil
+}
+
+func (f *fakeStatusStore) crawlStatus(dataset string, from, to time.Time) (map[string][]string, error) {
+	return f.statuses, nil
+}
+
+func TestFindGaps(t *testing.T) {
+	ss := &fakeStatusStore{
+		dates: map[string]bool{"2018-06-25": true, "2018-07-05": true},
+		statuses: map[string][]string{
+			"2018-06-26": {"otc:no_data", "tse:no_data"},
+			"2018-06-27": {"otc:ok", "tse:no_data"},
+			"2018-06-28": {"otc:no_data", "tse:error"},
+			"2018-06-29": {"otc:incomplete", "tse:no_data"},
+		},
+	}
+	traded := map[string]bool{"2018-06-25": true, "2018-07-02": true, "2018-07-05": true}
+	d := dataset{name: "quotes", table: "daily_quotes"}
+	from := time.Date(2018, 6, 20, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2018, 7, 5, 0, 0, 0, 0, time.UTC)
+
+	want := []gap{
+		{"2018-06-27", "otc:ok tse:no_data"},
+		{"2018-06-28", "otc:no_data tse:error"},
+		{"2018-06-29", "otc:incomplete tse:no_data"},
+		{"2018-07-02", "missing"},
+		{"2018-07-03", "never crawled"},
+		{"2018-07-04", "never crawled"},
+	}
+	gaps, err := findGaps(ss, d, from, to, false, traded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gaps, want) {
+		t.Errorf("findGaps = %v, want %v", gaps, want)
+	}
+
+	// from the flag, the days before the first date of the dataset too
+	gaps, err = findGaps(ss, d, from, to, true, traded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := []gap{{"2018-06-20", "never crawled"}, {"2018-06-21", "never crawled"}, {"2018-06-22", "never crawled"}}
+	if !reflect.DeepEqual(gaps, append(before, want...)) {
+		t.Errorf("findGaps from the flag = %v", gaps)
+	}
+}
+
+func TestRecordStatus(t *testing.T) {
+	date := time.Date(2018, 6, 29, 0, 0, 0, 0, time.UTC)
+	rows := [][]string{{"2330", "225"}}
+	failed := errors.New("503 Service Unavailable")
+	tests := []struct {
+		name    string
+		markets []marketRows
+		err     error
+		want    []string
+		fetch   error
+	}{
+		{"written", []marketRows{{"tse", nil, rows}, {"otc", nil, rows}}, nil, []string{"tse:ok", "otc:ok"}, nil},
+		{"holiday", []marketRows{{"tse", errNoData, nil}, {"otc", errNoData, nil}}, nil, []string{"tse:no_data", "otc:no_data"}, errNoData},
+		{"one market", []marketRows{{"tse", nil, rows}, {"otc", errNoData, nil}}, nil, []string{"tse:incomplete", "otc:no_data"}, errNoData},
+		{"fetch failed", []marketRows{{"tse", errNoData, nil}, {"otc", failed, nil}}, nil, []string{"tse:no_data", "otc:error"}, failed},
+		{"write failed", []marketRows{{"tse", nil, rows}}, failed, []string{"tse:error"}, nil},
+	}
+	for _, tt := range tests {
+		if err := fetchError(tt.markets); err != tt.fetch {
+			t.Errorf("%s: fetchError = %v, want %v", tt.name, err, tt.fetch)
+		}
+		ss := &fakeStatusStore{statuses: map[string][]string{}}
+		recordStatus(&statusOnly{ss}, "quotes", date, tt.markets, tt.err)
+		if got := ss.statuses["2018-06-29"]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// statusOnly is a Store which only keeps crawl_status.
+type statusOnly struct {
+	*fakeStatusStore
+}
+
+func (statusOnly) LastTradeDate(table string) (int, error)                      { return 0, nil }
+func (statusOnly) WriteQuotes(date time.Time, quotes []Quote) error             { return nil }
+func (statusOnly) WriteInvestors(date time.Time, investors []Investor) error    { return nil }
+func (statusOnly) WriteMarginShort(date time.Time, margins []MarginShort) error { return nil }
+func (statusOnly) Close() error                                                 { return nil }
@@ -0,0 +1,169 @@
+package main
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative twstock.proto
+
+import (
+	"flag"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func init() {
+	addCommand("grpc", "stream realtime ticks and daily loads over gRPC", runGRPC)
+}
+
+type grpcServer struct {
+	UnimplementedTWStockServer
+	poller *quotePoller
+	loads  *loadBroker
+}
+
+func runGRPC(args []string) {
+	flags := flag.NewFlagSet("grpc", flag.ExitOnError)
+	flagDSN := flags.String("dsn", "", "postgres data source name (default: the shared database)")
+	flagAddr := flags.String("addr", ":9090", "listen address")
+	flagInterval := flags.Duration("i", 5*time.Second, "mis.twse poll interval")
+	flags.Parse(args)
+
+	loads := newLoadBroker()
+	go loads.listen(postgresDSN(*flagDSN))
+	poller := newQuotePoller(*flagInterval)
+	go poller.run()
+
+	lis, err := net.Listen("tcp", *flagAddr)
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	s := grpc.NewServer()
+	RegisterTWStockServer(s, &grpcServer{poller: poller, loads: loads})
+	log.Println("listen", *flagAddr)
+	log.Fatal(s.Serve(lis))
+}
+
+func (s *grpcServer) StreamQuotes(req *Watchlist, stream grpc.ServerStreamingServer[Tick]) error {
+	if len(req.SecurityCodes) == 0 {
+		return status.Error(codes.InvalidArgument, "empty watchlist")
+	}
+	ch := s.poller.subscribe(req.SecurityCodes)
+	defer s.poller.unsubscribe(ch)
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case tick := <-ch:
+			if err := stream.Send(tick); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *grpcServer) SubscribeDailyLoad(req *DailyLoadRequest, stream grpc.ServerStreamingServer[DailyLoad]) error {
+	for _, name := range req.Datasets {
+		if len(selectDatasets(name)) == 0 {
+			return status.Errorf(codes.InvalidArgument, "unknown dataset %q", name)
+		}
+	}
+	ch := s.loads.subscribe(req.Datasets)
+	defer s.loads.unsubscribe(ch)
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case load := <-ch:
+			if err := stream.Send(load); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// loadBroker turns the notifications of the postgres store into DailyLoad
+// events, so crawls of any process reach the subscribers.
+type loadBroker struct {
+	mu          sync.Mutex
+	subscribers map[chan *DailyLoad]map[string]bool // empty for every dataset
+}
+
+func newLoadBroker() *loadBroker {
+	return &loadBroker{subscribers: make(map[chan *DailyLoad]map[string]bool)}
+}
+
+func (b *loadBroker) subscribe(names []string) chan *DailyLoad {
+	ch := make(chan *DailyLoad, 16)
+	filter := make(map[string]bool)
+	for _, name := range names {
+		filter[name] = true
+	}
+	b.mu.Lock()
+	b.subscribers[ch] = filter
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *loadBroker) unsubscribe(ch chan *DailyLoad) {
+	b.mu.Lock()
+	delete(b.subscribers, ch)
+	b.mu.Unlock()
+}
+
+func (b *loadBroker) publish(load *DailyLoad) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch, filter := range b.subscribers {
+		if len(filter) > 0 && !filter[load.Dataset] {
+			continue
+		}
+		select {
+		case ch <- load:
+		default:
+		}
+	}
+}
+
+func (b *loadBroker) listen(dsn string) {
+	listener := pq.NewListener(dsn, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Println(dailyLoadChannel, err)
+		}
+	})
+	if err := listener.Listen(dailyLoadChannel); err != nil {
+		log.Println(dailyLoadChannel, err)
+		os.Exit(1)
+	}
+	for n := range listener.Notify {
+		// nil after a reconnect
+		if n == nil {
+			continue
+		}
+		// daily_quotes 2018-06-29 1234
+		fields := strings.Fields(n.Extra)
+		if len(fields) != 3 {
+			continue
+		}
+		rows, _ := strconv.ParseInt(fields[2], 10, 64)
+		for _, d := range datasets {
+			if d.table == fields[0] {
+				b.publish(&DailyLoad{
+					Dataset:   d.name,
+					Table:     d.table,
+					TradeDate: fields[1],
+					Rows:      rows,
+					LoadedAt:  timestamppb.Now(),
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"time"
+)
+
+func init() {
+	addCommand("import", "load files written by 'crawl -db jsonl/csv' into a database", runImport)
+}
+
+func runImport(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	flagSource := flags.String("src", defaultDataDir, "directory of the files")
+	flagFormat := flags.String("format", "jsonl", "jsonl or csv")
+	flagStore := flags.String("db", "postgres", "store: postgres or sqlite")
+	flagDSN := flags.String("dsn", "", "data source name (default: the shared database, or "+defaultSQLiteFile+" for sqlite)")
+	flagDatasets := flags.String("d", "quotes,investors,margin", "datasets separated by comma")
+	flagFromDate := flags.Int("f", 0, "from date YYYYMMDD (default: all files)")
+	flagToDate := flags.Int("t", 0, "to date YYYYMMDD (default: all files)")
+	flags.Parse(args)
+
+	if *flagFormat != "jsonl" && *flagFormat != "csv" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	store, err := openStore(*flagStore, *flagDSN)
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	defer store.Close()
+	rs, ok := store.(rowStore)
+	if !ok {
+		log.Println(*flagStore, "can not be imported to")
+		os.Exit(1)
+	}
+
+	files := &fileStore{*flagSource, *flagFormat}
+	for _, d := range selectDatasets(*flagDatasets) {
+		paths, err := files.files(d.table)
+		if err != nil {
+			log.Println(d.name, err)
+			continue
+		}
+		for _, path := range paths {
+			date, err := fileDate(path)
+			if err != nil {
+				continue
+			}
+			if (*flagFromDate > 0 && date < *flagFromDate) || (*flagToDate > 0 && date > *flagToDate) {
+				continue
+			}
+			rows, err := readRows(path, *flagFormat, d.columns)
+			if err != nil {
+				log.Println(path, err)
+				continue
+			}
+			tradeDate := time.Date(date/10000, time.Month(date%10000/100), date%100, 0, 0, 0, 0, time.UTC)
+			if err := rs.replaceRows(d.table, tradeDate, d.columns, rows); err != nil {
+				log.Println(path, err)
+				continue
+			}
+			if d.table == "daily_quotes" {
+				if err := importShares(store, files, tradeDate); err != nil {
+					log.Println(path, err)
+				}
+				if err := aggregateDate(store, tradeDate); err != nil {
+					log.Println(path, err)
+				}
+			}
+			log.Println(path, len(rows), "rows")
+		}
+	}
+}
+
+// importShares loads the shares written with the quotes of a date, days
+// crawled before they were kept have none.
+func importShares(store Store, files *fileStore, date time.Time) error {
+	ss, ok := store.(sharesStore)
+	if !ok {
+		return nil
+	}
+	path := files.path("shares_outstanding", date)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	shares, err := readRows(path, files.format, sharesColumns)
+	if err != nil {
+		return err
+	}
+	return ss.writeShares(date, shares)
+}
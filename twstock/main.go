@@ -0,0 +1,53 @@
+package main
+
+// twstock runs the daily crawlers and the tools around the stored data
+// from one binary:
+//
+//	twstock crawl -db sqlite -dsn stock.db -l
+//	twstock crawl -d quotes,investors -f 20180101 -t 20180131
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string)
+}
+
+var commands = make(map[string]command)
+
+func addCommand(name, usage string, run func(args []string)) {
+	commands[name] = command{name, usage, run}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: twstock <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "run 'twstock <command> -h' for the flags of a command")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	cmd.run(os.Args[2:])
+}
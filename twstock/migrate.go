@@ -0,0 +1,256 @@
+package main
+
+import (
+	"database/sql"
+	"embed"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrations/NNNN_name.up.sql and NNNN_name.down.sql, applied in order of
+// NNNN.  Never edit an applied migration, add a new one.
+//
+// migrations/timescale are only applied with -timescale, they need the
+// TimescaleDB extension and are numbered from 1001.
+//
+//go:embed migrations/*.sql migrations/timescale/*.sql
+var migrationFiles embed.FS
+
+const timescaleMigrations = "migrations/timescale"
+
+const schemaVersionSql = `CREATE TABLE IF NOT EXISTS schema_version (
+	version		integer PRIMARY KEY,
+	name		varchar,
+	applied_at	timestamp DEFAULT now()
+)`
+
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+func init() {
+	addCommand("migrate", "apply database migrations: up, down or status", runMigrate)
+}
+
+func loadMigrations(dirs ...string) ([]migration, error) {
+	byVersion := make(map[int]*migration)
+	for _, dir := range dirs {
+		entries, err := migrationFiles.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			// 0001_base.up.sql
+			name := entry.Name()
+			parts := strings.SplitN(name, "_", 2)
+			version, err := strconv.Atoi(parts[0])
+			if err != nil || len(parts) != 2 {
+				return nil, fmt.Errorf("bad migration name %s", name)
+			}
+			contents, err := migrationFiles.ReadFile(path.Join(dir, name))
+			if err != nil {
+				return nil, err
+			}
+			m, ok := byVersion[version]
+			if !ok {
+				m = &migration{version: version}
+				byVersion[version] = m
+			}
+			switch {
+			case strings.HasSuffix(name, ".up.sql"):
+				m.name = strings.TrimSuffix(parts[1], ".up.sql")
+				m.up = string(contents)
+			case strings.HasSuffix(name, ".down.sql"):
+				m.down = string(contents)
+			}
+		}
+	}
+
+	var migrations []migration
+	for _, m := range byVersion {
+		if len(m.up) == 0 {
+			return nil, fmt.Errorf("migration %04d has no up", m.version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+func appliedVersions(db *sql.DB) (map[int]time.Time, error) {
+	if _, err := db.Exec(schemaVersionSql); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT version, applied_at FROM schema_version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// execMigration runs one migration and records it in the same transaction.
+func execMigration(db *sql.DB, m migration, up bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if up {
+		if _, err := tx.Exec(m.up); err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO schema_version (version, name) VALUES ($1, $2)", m.version, m.name)
+	} else {
+		if _, err := tx.Exec(m.down); err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM schema_version WHERE version = $1", m.version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func runMigrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	flagDSN := flags.String("dsn", "", "postgres data source name (default: the shared database)")
+	flagSteps := flags.Int("n", 0, "number of migrations to apply (default: all for up, 1 for down)")
+	flagTimescale := flags.Bool("timescale", false, "include the TimescaleDB hypertables, continuous aggregates and compression")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: twstock migrate [flags] up|down|status")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	dirs := []string{"migrations"}
+	if *flagTimescale {
+		dirs = append(dirs, timescaleMigrations)
+	}
+	migrations, err := loadMigrations(dirs...)
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+
+	db, err := openPostgresDB(*flagDSN)
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	switch flags.Arg(0) {
+	case "up":
+		count, err := migrateUp(db, migrations, *flagSteps)
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		log.Println(count, "migrations applied")
+	case "down":
+		steps := *flagSteps
+		if steps == 0 {
+			steps = 1
+		}
+		if err := migrateDown(db, migrations, steps); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+	case "status":
+		applied, err := appliedVersions(db)
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		for _, m := range migrations {
+			if appliedAt, ok := applied[m.version]; ok {
+				fmt.Printf("%04d %-20s applied %s\n", m.version, m.name, appliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Printf("%04d %-20s pending\n", m.version, m.name)
+			}
+		}
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+}
+
+// migrateUp applies the pending migrations in order, all of them when steps
+// is 0, and returns how many were applied.
+func migrateUp(db *sql.DB, migrations []migration, steps int) (int, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+		if steps > 0 && count == steps {
+			break
+		}
+		log.Printf("up %04d %s\n", m.version, m.name)
+		if err := execMigration(db, m, true); err != nil {
+			return count, fmt.Errorf("%04d %s: %v", m.version, m.name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// migrateDown reverts the last steps applied migrations.  1001_hypertables
+// can not be reverted, its down fails and leaves it applied.
+func migrateDown(db *sql.DB, migrations []migration, steps int) error {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return err
+	}
+	// the timescale migrations sit on top of the others
+	for version := range applied {
+		if version > migrations[len(migrations)-1].version {
+			return fmt.Errorf("%04d is applied, run with -timescale", version)
+		}
+	}
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.version]; !ok {
+			continue
+		}
+		log.Printf("down %04d %s\n", m.version, m.name)
+		if err := execMigration(db, m, false); err != nil {
+			return fmt.Errorf("%04d %s: %v", m.version, m.name, err)
+		}
+		steps--
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestTimescaleMigrations runs 'migrate -timescale up' and down again on
+// TWSTOCK_TIMESCALE_DSN, a scratch TimescaleDB database, e.g. the docker
+// container of the README.  The database is left at 1001, which can not be
+// reverted.
+func TestTimescaleMigrations(t *testing.T) {
+	dsn := os.Getenv("TWSTOCK_TIMESCALE_DSN")
+	if len(dsn) == 0 {
+		t.Skip("TWSTOCK_TIMESCALE_DSN is not set")
+	}
+	db, err := openPostgresDB(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrations, err := loadMigrations("migrations", timescaleMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrateUp(db, migrations, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	hypertables := "daily_quotes indices_5seconds trades_5seconds"
+	if got := queryNames(t, db, "SELECT hypertable_name FROM timescaledb_information.hypertables"); got != hypertables {
+		t.Errorf("hypertables %q, want %q", got, hypertables)
+	}
+	caggs := "SELECT view_name FROM timescaledb_information.continuous_aggregates"
+	if got := queryNames(t, db, caggs); got != "quotes_monthly quotes_weekly" {
+		t.Errorf("continuous aggregates %q", got)
+	}
+	policies := "SELECT hypertable_name FROM timescaledb_information.jobs WHERE proc_name = 'policy_compression'"
+	if got := queryNames(t, db, policies); got != hypertables {
+		t.Errorf("compression policies on %q, want %q", got, hypertables)
+	}
+
+	// 1003_compression and 1002_continuous_aggregates
+	if err := migrateDown(db, migrations, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := queryNames(t, db, caggs); got != "" {
+		t.Errorf("continuous aggregates %q after down", got)
+	}
+	if got := queryNames(t, db, policies); got != "" {
+		t.Errorf("compression policies on %q after down", got)
+	}
+
+	if err := migrateDown(db, migrations, 1); err == nil {
+		t.Error("1001_hypertables reverted")
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := applied[1001]; !ok {
+		t.Error("1001_hypertables not applied after a failed down")
+	}
+}
+
+// queryNames returns the sorted names of a query separated by space.
+func queryNames(t *testing.T, db *sql.DB, query string) string {
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
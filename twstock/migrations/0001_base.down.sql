@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS sectors;
+DROP TABLE IF EXISTS indices;
+DROP TABLE IF EXISTS index_margin_short;
+DROP TABLE IF EXISTS index_investors;
+DROP TABLE IF EXISTS index_values;
+DROP TABLE IF EXISTS daily_margin_short;
+DROP TABLE IF EXISTS trades_5seconds;
+DROP TABLE IF EXISTS indices_5seconds;
+DROP TABLE IF EXISTS daily_investors;
+DROP TABLE IF EXISTS day_trade_indices;
+DROP TABLE IF EXISTS day_trade_securities;
+DROP TABLE IF EXISTS daily_indices;
+DROP TABLE IF EXISTS daily_quotes;
@@ -1,4 +1,8 @@
-CREATE TABLE daily_quotes (
+-- Base tables of the daily crawlers, merged from SQL/tse.sql, SQL/index.sql,
+-- SQL/margin.sql, SQL/day_trade.sql, SQL/5min.sql and SQL/sector.sql.
+-- IF NOT EXISTS lets a database created by those scripts adopt this version.
+
+CREATE TABLE IF NOT EXISTS daily_quotes (
 	trade_date      date,    -- trade date
 	security_code   varchar,
 	close_price     numeric,
@@ -17,7 +21,7 @@ CREATE TABLE daily_quotes (
 	UNIQUE (trade_date, security_code)
 );
 
-CREATE TABLE daily_indices (
+CREATE TABLE IF NOT EXISTS daily_indices (
 	trade_date      date,    -- trade date
 	security_code   varchar,
 	index_value		numeric,
@@ -27,7 +31,7 @@ CREATE TABLE daily_indices (
 	UNIQUE (trade_date, security_code)
 );
 
-CREATE TABLE day_trade_securities (
+CREATE TABLE IF NOT EXISTS day_trade_securities (
 	trade_date      date,    -- trade date
 	security_code   varchar,
 	volume			numeric,
@@ -36,7 +40,7 @@ CREATE TABLE day_trade_securities (
 	UNIQUE (trade_date, security_code)
 );
 
-CREATE TABLE day_trade_indices (
+CREATE TABLE IF NOT EXISTS day_trade_indices (
 	trade_date      date,    -- trade date
 	security_code   varchar,
 	volume			numeric,
@@ -51,7 +55,7 @@ CREATE TABLE day_trade_indices (
 
 --  Trading Volume of Foreign & Other Investors (Share)
 
-CREATE TABLE daily_investors (
+CREATE TABLE IF NOT EXISTS daily_investors (
 	trade_date      date,    -- trade date
 	security_code   varchar,
 	foreign_buy		numeric,
@@ -68,25 +72,23 @@ CREATE TABLE daily_investors (
 	dealer_hedge_sell	numeric,
 	dealer_hedge_diff	numeric,
 	investors_diff	numeric,
+	foreign_self_buy	numeric,  -- foreign dealer (proprietary)
+	foreign_self_sell	numeric,
+	foreign_self_diff	numeric,
 	UNIQUE (trade_date, security_code)
 );
 
-ALTER TABLE daily_investors
-    ADD foreign_self_buy    numeric,
-    ADD foreign_self_sell   numeric,
-    ADD foreign_self_diff   numeric;
-
 
 -- TAIEX & Group Indices per 5 Seconds
 
-CREATE TABLE indices_5seconds (
+CREATE TABLE IF NOT EXISTS indices_5seconds (
 	trade_datetime	timestamp,
 	security_code	varchar,
 	index_value		numeric,
 	UNIQUE (trade_datetime, security_code)
 );
 
-CREATE TABLE trades_5seconds (
+CREATE TABLE IF NOT EXISTS trades_5seconds (
 	trade_datetime	timestamp,
 	security_code	varchar,
 	acc_bid_order	numeric,
@@ -109,7 +111,7 @@ CREATE TABLE trades_5seconds (
 
 -- Margin Transaction 融資Purchase on Margin/融券Short Sale
 
-CREATE TABLE daily_margin_short (
+CREATE TABLE IF NOT EXISTS daily_margin_short (
 	trade_date		date,
 	security_code	varchar,
 	margin_new			numeric,	-- 融資買進
@@ -131,20 +133,20 @@ CREATE TABLE daily_margin_short (
 
 -- Indices information (TAIEX/OTC...)
 
-CREATE TABLE index_values (
+CREATE TABLE IF NOT EXISTS index_values (
 	trade_date		date,
 	index_code		varchar,	-- TAIEX / OTC
-	close_value     numeric,
 	open_value      numeric,
 	highest_value   numeric,
 	lowest_value    numeric,
+	close_value     numeric,
 	trade_volume	numeric,  -- shares
 	trade_amount    numeric,
 	trade_count     numeric,  -- transcation
 	UNIQUE (trade_date, index_code)
 );
 
-CREATE TABLE index_investors (
+CREATE TABLE IF NOT EXISTS index_investors (
 	trade_date			date,
 	index_code			varchar,	-- TAIEX / OTC
 	dealer_self_buy		numeric,  -- dealer (proprietary)
@@ -162,15 +164,13 @@ CREATE TABLE index_investors (
 	total_buy			numeric,
 	total_sell			numeric,
 	total_diff			numeric,
+	foreign_self_buy	numeric,
+	foreign_self_sell	numeric,
+	foreign_self_diff	numeric,
 	UNIQUE (trade_date, index_code)
 );
 
-ALTER TABLE index_investors
-	ADD foreign_self_buy	numeric,
-	ADD foreign_self_sell	numeric,
-	ADD foreign_self_diff	numeric;
-
-CREATE TABLE index_margin_short (
+CREATE TABLE IF NOT EXISTS index_margin_short (
 	trade_date			date,
 	index_code			varchar,	-- TAIEX / OTC
 	margin_new			numeric,	-- 融資買進
@@ -191,3 +191,68 @@ CREATE TABLE index_margin_short (
 	UNIQUE (trade_date, index_code)
 );
 
+
+-- databases created before the foreign dealer columns
+
+ALTER TABLE daily_investors
+	ADD COLUMN IF NOT EXISTS foreign_self_buy	numeric,
+	ADD COLUMN IF NOT EXISTS foreign_self_sell	numeric,
+	ADD COLUMN IF NOT EXISTS foreign_self_diff	numeric;
+
+ALTER TABLE index_investors
+	ADD COLUMN IF NOT EXISTS foreign_self_buy	numeric,
+	ADD COLUMN IF NOT EXISTS foreign_self_sell	numeric,
+	ADD COLUMN IF NOT EXISTS foreign_self_diff	numeric;
+
+
+-- Index names of MI_INDEX, read by TSE/dailyquote.go
+
+CREATE TABLE IF NOT EXISTS indices (
+	security_code	varchar,
+	name			varchar,
+	UNIQUE (security_code)
+);
+
+
+-- Sectors 產業別
+
+CREATE TABLE IF NOT EXISTS sectors (
+	id		integer,
+	name	varchar,
+	UNIQUE(id)
+);
+
+INSERT INTO sectors VALUES
+('01','水泥工業'),
+('02','食品工業'),
+('03','塑膠工業'),
+('04','紡織纖維'),
+('05','電機機械'),
+('06','電器電纜'),
+('07','化學生技醫療'),
+('21','化學工業'),
+('22','生技醫療業'),
+('08','玻璃陶瓷'),
+('09','造紙工業'),
+('10','鋼鐵工業'),
+('11','橡膠工業'),
+('12','汽車工業'),
+('13','電子工業'),
+('24','半導體業'),
+('25','電腦及週邊設備業'),
+('26','光電業'),
+('27','通信網路業'),
+('28','電子零組件業'),
+('29','電子通路業'),
+('30','資訊服務業'),
+('31','其他電子業'),
+('14','建材營造'),
+('15','航運業'),
+('16','觀光事業'),
+('17','金融保險'),
+('18','貿易百貨'),
+('9299','存託憑證'),
+('23','油電燃氣業'),
+('19','綜合'),
+('20','其他')
+ON CONFLICT DO NOTHING;
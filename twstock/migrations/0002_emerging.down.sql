@@ -0,0 +1 @@
+DROP TABLE IF EXISTS emerging_quotes;
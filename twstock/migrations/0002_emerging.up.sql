@@ -0,0 +1,20 @@
+-- TPEx Emerging Stock Board 興櫃 (quoted by recommending brokers, no open/close auction)
+
+CREATE TABLE IF NOT EXISTS emerging_quotes (
+	trade_date      date,    -- trade date
+	security_code   varchar,
+	last_average_price	numeric,	-- 前日均價
+	last_bid_price  numeric,	-- 報買價
+	last_bid_volume numeric,
+	last_ask_price  numeric,	-- 報賣價
+	last_ask_volume numeric,
+	highest_price   numeric,
+	lowest_price    numeric,
+	average_price   numeric,	-- 日均價
+	close_price     numeric,	-- 最後成交價
+	trade_volume	numeric,  -- shares
+	trade_amount    numeric,
+	trade_count     numeric,  -- transcation
+	CHECK (highest_price >= lowest_price),
+	UNIQUE (trade_date, security_code)
+);
@@ -0,0 +1,22 @@
+-- Warrants 權證 and Callable Bull/Bear Contracts 牛熊證 (TSE type=0999, OTC)
+
+CREATE TABLE IF NOT EXISTS warrant_quotes (
+	trade_date      date,    -- trade date
+	security_code   varchar,
+	close_price     numeric,
+	open_price      numeric,
+	highest_price   numeric,
+	lowest_price    numeric,
+	trade_volume	numeric,  -- shares
+	trade_amount    numeric,
+	trade_count     numeric,  -- transcation
+	last_bid_price  numeric,
+	last_bid_volume numeric,
+	last_ask_price  numeric,
+	last_ask_volume numeric,
+	underlying_code	varchar,	-- 標的代號
+	strike_price	numeric,	-- 履約價格
+	expiry_date		date,		-- 履約截止日
+	exercise_ratio	numeric,	-- 行使比例 (per 1000 units)
+	UNIQUE (trade_date, security_code)
+);
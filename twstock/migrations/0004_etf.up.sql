@@ -0,0 +1,13 @@
+-- ETF Net Asset Value 淨值 and Premium/Discount 折溢價, ETNs are not covered
+
+CREATE TABLE IF NOT EXISTS etf_nav (
+	trade_date			date,
+	security_code		varchar,
+	units_outstanding	numeric,	-- 已發行受益權單位數
+	units_change		numeric,	-- 與前日已發行單位差異數 (creation - redemption)
+	estimated_nav		numeric,	-- 投資組合預估淨值
+	nav					numeric,	-- 單位淨值 (published on next trade date)
+	close_price			numeric,	-- daily_quotes.close_price
+	premium_discount	numeric,	-- 折溢價 (%)
+	UNIQUE (trade_date, security_code)
+);
@@ -0,0 +1,2 @@
+DROP MATERIALIZED VIEW IF EXISTS adjusted_quotes;
+DROP TABLE IF EXISTS corporate_actions;
@@ -0,0 +1,35 @@
+-- Ex-Rights/Ex-Dividend 除權除息 (TSE TWT49U, OTC exDailyQ)
+
+CREATE TABLE IF NOT EXISTS corporate_actions (
+	ex_date			date,
+	security_code	varchar,
+	action_type		varchar,	-- 權 / 息 / 權息
+	before_price	numeric,	-- 除權息前收盤價
+	reference_price	numeric,	-- 除權息參考價
+	rights_value	numeric,	-- 權值+息值
+	cash_dividend	numeric,	-- 現金股利 (per share)
+	stock_dividend	numeric,	-- 無償配股 (shares per 1000 shares)
+	UNIQUE (ex_date, security_code)
+);
+
+-- Back-adjusted quotes, prices before each ex-date are scaled by
+-- reference_price / before_price. REFRESH ... CONCURRENTLY, which needs the
+-- unique index, after corporate_actions or daily_quotes change.
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS adjusted_quotes AS
+SELECT q.trade_date, q.security_code,
+	round(q.open_price * f.factor, 2) AS open_price,
+	round(q.highest_price * f.factor, 2) AS highest_price,
+	round(q.lowest_price * f.factor, 2) AS lowest_price,
+	round(q.close_price * f.factor, 2) AS close_price,
+	round(q.trade_volume / f.factor) AS trade_volume,
+	f.factor AS adjust_factor
+FROM daily_quotes q
+CROSS JOIN LATERAL (
+	SELECT COALESCE(exp(sum(ln(a.reference_price / a.before_price))), 1) AS factor
+	FROM corporate_actions a
+	WHERE a.security_code = q.security_code AND a.ex_date > q.trade_date
+		AND a.before_price > 0 AND a.reference_price > 0
+) f;
+
+CREATE UNIQUE INDEX IF NOT EXISTS adjusted_quotes_date_code ON adjusted_quotes (trade_date, security_code);
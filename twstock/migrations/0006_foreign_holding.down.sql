@@ -0,0 +1 @@
+DROP TABLE IF EXISTS foreign_holdings;
@@ -0,0 +1,14 @@
+-- Foreign & Mainland Area Investors Shareholding 外資及陸資投資持股統計 (MI_QFIIS)
+
+CREATE TABLE IF NOT EXISTS foreign_holdings (
+	trade_date			date,
+	security_code		varchar,
+	issued_shares		numeric,	-- 發行股數
+	available_shares	numeric,	-- 尚可投資股數
+	holding_shares		numeric,	-- 全體外資及陸資持有股數
+	available_ratio		numeric,	-- 尚可投資比率 (%)
+	holding_ratio		numeric,	-- 持股比率 (%)
+	upper_limit_ratio	numeric,	-- 共用法令投資上限比率 (%)
+	china_upper_limit_ratio	numeric,	-- 陸資法令投資上限比率 (%)
+	UNIQUE (trade_date, security_code)
+);
@@ -0,0 +1,2 @@
+DROP VIEW IF EXISTS short_interest;
+DROP TABLE IF EXISTS daily_sbl_short;
@@ -0,0 +1,25 @@
+-- Securities Borrowing and Lending short sale 借券賣出 (TWT93U), in shares
+
+CREATE TABLE IF NOT EXISTS daily_sbl_short (
+	trade_date		date,
+	security_code	varchar,
+	sbl_last_remain	numeric,	-- 前日餘額
+	sbl_sell		numeric,	-- 當日賣出
+	sbl_return		numeric,	-- 當日還券
+	sbl_adjust		numeric,	-- 當日調整
+	sbl_remain		numeric,	-- 當日餘額
+	sbl_limit		numeric,	-- 次一營業日可限額
+	UNIQUE (trade_date, security_code)
+);
+
+-- Short interest in shares, daily_margin_short is counted in lots (1000 shares)
+
+CREATE OR REPLACE VIEW short_interest AS
+SELECT COALESCE(m.trade_date, s.trade_date) AS trade_date,
+	COALESCE(m.security_code, s.security_code) AS security_code,
+	m.short_remain * 1000 AS short_remain,
+	s.sbl_remain,
+	COALESCE(m.short_remain * 1000, 0) + COALESCE(s.sbl_remain, 0) AS total_short
+FROM daily_margin_short m
+FULL OUTER JOIN daily_sbl_short s
+	ON s.trade_date = m.trade_date AND s.security_code = m.security_code;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS broker_trades;
+DROP TABLE IF EXISTS brokers;
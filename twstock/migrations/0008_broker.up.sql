@@ -0,0 +1,17 @@
+-- Broker Branch Trading 券商分點進出 (bsr)
+
+CREATE TABLE IF NOT EXISTS brokers (
+	broker_code		varchar,	-- 券商代號 (branch)
+	broker_name		varchar,
+	UNIQUE (broker_code)
+);
+
+CREATE TABLE IF NOT EXISTS broker_trades (
+	trade_date		date,
+	security_code	varchar,
+	broker_code		varchar,
+	price			numeric,	-- 成交單價
+	buy_volume		numeric,	-- 買進股數
+	sell_volume		numeric,	-- 賣出股數
+	UNIQUE (trade_date, security_code, broker_code, price)
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS put_call_ratios;
+DROP TABLE IF EXISTS options_investors;
+DROP TABLE IF EXISTS futures_investors;
+DROP TABLE IF EXISTS futures_quotes;
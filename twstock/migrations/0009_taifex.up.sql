@@ -0,0 +1,70 @@
+-- Taiwan Futures Exchange TAIFEX
+
+CREATE TABLE IF NOT EXISTS futures_quotes (
+	trade_date		date,
+	contract		varchar,	-- TX / MTX
+	expiry_month	varchar,	-- 到期月份(週別)
+	trade_session	varchar,	-- regular / after_hours
+	open_price		numeric,
+	highest_price	numeric,
+	lowest_price	numeric,
+	close_price		numeric,
+	trade_volume	numeric,	-- contracts
+	settlement_price	numeric,	-- 結算價
+	open_interest	numeric,	-- 未沖銷契約數
+	UNIQUE (trade_date, contract, expiry_month, trade_session)
+);
+
+-- 三大法人 futures positions, amount in thousands
+
+CREATE TABLE IF NOT EXISTS futures_investors (
+	trade_date		date,
+	contract		varchar,	-- 商品名稱
+	investor_type	varchar,	-- dealer / trust / foreign
+	long_volume		numeric,	-- 多方交易口數
+	long_amount		numeric,
+	short_volume	numeric,	-- 空方交易口數
+	short_amount	numeric,
+	net_volume		numeric,
+	net_amount		numeric,
+	long_oi			numeric,	-- 多方未平倉口數
+	long_oi_amount	numeric,
+	short_oi		numeric,	-- 空方未平倉口數
+	short_oi_amount	numeric,
+	net_oi			numeric,
+	net_oi_amount	numeric,
+	UNIQUE (trade_date, contract, investor_type)
+);
+
+-- 三大法人 options positions, long = 買方, short = 賣方
+
+CREATE TABLE IF NOT EXISTS options_investors (
+	trade_date		date,
+	contract		varchar,
+	call_put		varchar,	-- call / put
+	investor_type	varchar,	-- dealer / trust / foreign
+	long_volume		numeric,
+	long_amount		numeric,
+	short_volume	numeric,
+	short_amount	numeric,
+	net_volume		numeric,
+	net_amount		numeric,
+	long_oi			numeric,
+	long_oi_amount	numeric,
+	short_oi		numeric,
+	short_oi_amount	numeric,
+	net_oi			numeric,
+	net_oi_amount	numeric,
+	UNIQUE (trade_date, contract, call_put, investor_type)
+);
+
+CREATE TABLE IF NOT EXISTS put_call_ratios (
+	trade_date		date,
+	put_volume		numeric,	-- 賣權成交量
+	call_volume		numeric,	-- 買權成交量
+	volume_ratio	numeric,	-- 買賣權成交量比率 (%)
+	put_oi			numeric,	-- 賣權未平倉量
+	call_oi			numeric,	-- 買權未平倉量
+	oi_ratio		numeric,	-- 買賣權未平倉量比率 (%)
+	UNIQUE (trade_date)
+);
@@ -0,0 +1,2 @@
+DROP VIEW IF EXISTS total_trades;
+DROP TABLE IF EXISTS session_trades;
@@ -0,0 +1,23 @@
+-- Block 鉅額 (BFIAUU), Odd-Lot 盤後零股 (TWT53U) and After-Hours Fixed Price 盤後定價 (BFT41U) trades
+
+CREATE TABLE IF NOT EXISTS session_trades (
+	trade_date		date,
+	security_code	varchar,
+	trade_session	varchar,	-- block / odd_lot / fixed_price
+	trade_volume	numeric,	-- shares
+	trade_count		numeric,	-- transcation
+	trade_amount	numeric,
+	trade_price		numeric,	-- last trade price
+	UNIQUE (trade_date, security_code, trade_session)
+);
+
+-- Total volume per security including all sessions
+
+CREATE OR REPLACE VIEW total_trades AS
+SELECT q.trade_date, q.security_code,
+	q.trade_volume + COALESCE(SUM(s.trade_volume), 0) AS trade_volume,
+	q.trade_count + COALESCE(SUM(s.trade_count), 0) AS trade_count,
+	q.trade_amount + COALESCE(SUM(s.trade_amount), 0) AS trade_amount
+FROM daily_quotes q
+LEFT JOIN session_trades s ON s.trade_date = q.trade_date AND s.security_code = q.security_code
+GROUP BY q.trade_date, q.security_code, q.trade_volume, q.trade_count, q.trade_amount;
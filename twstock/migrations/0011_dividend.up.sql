@@ -0,0 +1,16 @@
+-- Dividend Policy 股利分派情形 (MOPS t05st09_2), dividends per share
+
+CREATE TABLE IF NOT EXISTS dividend_policies (
+	security_code	varchar,
+	dividend_year	integer,	-- year of resolution
+	period			varchar,	-- 股利所屬期間
+	board_date		date,		-- 董事會決議(擬議)股利分派日
+	agm_date		date,		-- 股東會日期
+	cash_earnings	numeric,	-- 盈餘分配之現金股利
+	cash_reserve	numeric,	-- 法定盈餘公積、資本公積發放之現金
+	stock_earnings	numeric,	-- 盈餘轉增資配股
+	stock_reserve	numeric,	-- 法定盈餘公積、資本公積轉增資配股
+	ex_date			date,		-- 除息交易日
+	payment_date	date,		-- 現金股利發放日
+	UNIQUE (security_code, dividend_year, period)
+);
@@ -0,0 +1,18 @@
+-- Material Information 重大訊息 (MOPS t05st02)
+
+CREATE TABLE IF NOT EXISTS announcements (
+	security_code	varchar,
+	spoke_time		timestamp,	-- 發言日期 發言時間
+	seq_no			varchar,	-- 序號, an announcement may be updated on the same time
+	subject			varchar,	-- 主旨
+	body			text,		-- 說明
+	category		varchar,	-- 符合條款
+	fact_date		date,		-- 事實發生日
+	UNIQUE (security_code, spoke_time, seq_no)
+);
+
+-- trigram index for Chinese keyword search, e.g.
+-- SELECT * FROM announcements WHERE subject || ' ' || body ILIKE '%庫藏股%';
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS announcements_text_idx ON announcements USING gin ((subject || ' ' || body) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS announcements_code_idx ON announcements (security_code, spoke_time);
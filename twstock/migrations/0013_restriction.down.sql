@@ -0,0 +1,2 @@
+DROP VIEW IF EXISTS restricted_quotes;
+DROP TABLE IF EXISTS trading_restrictions;
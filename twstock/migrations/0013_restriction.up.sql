@@ -0,0 +1,27 @@
+-- Attention 注意股票, Disposition 處置股票 and Suspension 暫停交易 (TSE and OTC)
+
+CREATE TABLE IF NOT EXISTS trading_restrictions (
+	security_code		varchar,
+	restriction_type	varchar,	-- attention / disposition / suspension
+	market				varchar,	-- tse / otc
+	start_date			date,
+	end_date			date,		-- null if still suspended
+	reason				varchar,	-- 注意交易資訊 / 處置條件 / 停止交易原因
+	UNIQUE (security_code, restriction_type, start_date)
+);
+
+CREATE INDEX IF NOT EXISTS trading_restrictions_range ON trading_restrictions (security_code, start_date, end_date);
+
+-- restrictions in effect on each trade date, e.g. to exclude from screens:
+-- SELECT q.* FROM daily_quotes q LEFT JOIN restricted_quotes r USING (trade_date, security_code)
+-- WHERE r.disposition IS NOT TRUE;
+
+CREATE OR REPLACE VIEW restricted_quotes AS
+SELECT q.trade_date, q.security_code,
+	bool_or(r.restriction_type = 'attention') AS attention,
+	bool_or(r.restriction_type = 'disposition') AS disposition,
+	bool_or(r.restriction_type = 'suspension') AS suspension
+FROM daily_quotes q
+JOIN trading_restrictions r ON r.security_code = q.security_code
+	AND q.trade_date >= r.start_date AND (r.end_date IS NULL OR q.trade_date <= r.end_date)
+GROUP BY q.trade_date, q.security_code;
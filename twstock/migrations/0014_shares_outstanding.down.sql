@@ -0,0 +1,2 @@
+DROP VIEW IF EXISTS market_caps;
+DROP TABLE IF EXISTS shares_outstanding;
@@ -0,0 +1,22 @@
+-- Shares outstanding 發行股數, OTC from the daily quotes (src/dailyquote.go and
+-- 'twstock crawl'), TSE from the MOPS company information (MOPS/sharesoutstanding.go)
+
+CREATE TABLE IF NOT EXISTS shares_outstanding (
+	trade_date		date,
+	security_code	varchar,
+	shares			numeric,
+	UNIQUE (trade_date, security_code)
+);
+
+-- market cap and turnover ratio with the latest known shares on each trade date
+
+CREATE OR REPLACE VIEW market_caps AS
+SELECT q.trade_date, q.security_code, q.close_price, s.shares,
+	q.close_price * s.shares AS market_cap,
+	round(q.trade_volume / NULLIF(s.shares, 0) * 100, 4) AS turnover_ratio	-- %
+FROM daily_quotes q
+LEFT JOIN LATERAL (
+	SELECT o.shares FROM shares_outstanding o
+	WHERE o.security_code = q.security_code AND o.trade_date <= q.trade_date
+	ORDER BY o.trade_date DESC LIMIT 1
+) s ON true;
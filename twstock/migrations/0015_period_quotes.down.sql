@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS monthly_quotes;
+DROP TABLE IF EXISTS weekly_quotes;
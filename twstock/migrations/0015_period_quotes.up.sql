@@ -0,0 +1,31 @@
+-- Weekly/monthly bars of daily_quotes written by 'twstock aggregate' and
+-- after every crawl, trade_date is the first day of the period (Monday or
+-- the 1st).
+
+CREATE TABLE IF NOT EXISTS weekly_quotes (
+	trade_date		date,
+	security_code	varchar,
+	open_price		numeric,	-- first open of the week
+	highest_price	numeric,
+	lowest_price	numeric,
+	close_price		numeric,	-- last close of the week
+	trade_volume	numeric,
+	trade_amount	numeric,
+	trade_count		numeric,
+	trade_days		numeric,	-- days with a close price
+	UNIQUE (trade_date, security_code)
+);
+
+CREATE TABLE IF NOT EXISTS monthly_quotes (
+	trade_date		date,
+	security_code	varchar,
+	open_price		numeric,
+	highest_price	numeric,
+	lowest_price	numeric,
+	close_price		numeric,
+	trade_volume	numeric,
+	trade_amount	numeric,
+	trade_count		numeric,
+	trade_days		numeric,
+	UNIQUE (trade_date, security_code)
+);
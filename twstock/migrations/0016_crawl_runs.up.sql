@@ -0,0 +1,15 @@
+-- one row per dataset and trade date run by 'twstock daemon'
+
+CREATE TABLE IF NOT EXISTS crawl_runs (
+	id				serial PRIMARY KEY,
+	dataset			varchar,	-- quotes / investors / margin
+	trade_date		date,
+	started_at		timestamp,
+	finished_at		timestamp,
+	attempts		integer,
+	status			varchar,	-- ok / no_data / error
+	row_count		integer,
+	error			varchar
+);
+
+CREATE INDEX IF NOT EXISTS crawl_runs_dataset_date ON crawl_runs (dataset, trade_date);
@@ -0,0 +1,17 @@
+-- Every crawled trade date per dataset and market, so 'twstock gaps' can
+-- tell a holiday (no_data) from a day which was never fetched.
+--	ok			written, row_count rows with checksum
+--	no_data		the exchange had nothing for the date
+--	incomplete	fetched, but not written as the other market had no data
+--	error		the write failed
+
+CREATE TABLE IF NOT EXISTS crawl_status (
+	dataset			varchar,	-- quotes / investors / margin
+	market			varchar,	-- tse / otc
+	trade_date		date,
+	status			varchar,
+	row_count		integer,
+	fetched_at		timestamp,
+	checksum		varchar,	-- sha256 of the rows
+	UNIQUE (dataset, market, trade_date)
+);
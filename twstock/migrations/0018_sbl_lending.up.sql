@@ -0,0 +1,13 @@
+-- Securities Borrowing and Lending balance of the SBL system and the brokers
+-- (TWT72U), every borrowed share in shares, daily_sbl_short is the part sold short
+
+CREATE TABLE IF NOT EXISTS daily_sbl_lending (
+	trade_date			date,
+	security_code		varchar,
+	lending_last_remain	numeric,	-- 前日餘額
+	lending				numeric,	-- 本日借券
+	lending_return		numeric,	-- 本日還券
+	lending_adjust		numeric,	-- 本日調整
+	lending_remain		numeric,	-- 本日餘額
+	UNIQUE (trade_date, security_code)
+);
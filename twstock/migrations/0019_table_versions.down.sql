@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS daily_quotes_version ON daily_quotes;
+DROP TRIGGER IF EXISTS daily_investors_version ON daily_investors;
+DROP TRIGGER IF EXISTS daily_margin_short_version ON daily_margin_short;
+DROP TRIGGER IF EXISTS daily_indices_version ON daily_indices;
+DROP TRIGGER IF EXISTS weekly_quotes_version ON weekly_quotes;
+DROP TRIGGER IF EXISTS monthly_quotes_version ON monthly_quotes;
+DROP TRIGGER IF EXISTS index_values_version ON index_values;
+DROP TRIGGER IF EXISTS index_investors_version ON index_investors;
+DROP TRIGGER IF EXISTS index_margin_short_version ON index_margin_short;
+DROP FUNCTION IF EXISTS bump_table_version();
+DROP TABLE IF EXISTS table_versions;
@@ -0,0 +1,52 @@
+-- Write counter per table for the ETags of 'twstock serve'.  A date
+-- rewritten in place keeps its row count and last date, the counter of the
+-- statement triggers changes with every writer, not only twstock.
+
+CREATE TABLE IF NOT EXISTS table_versions (
+	table_name	varchar PRIMARY KEY,
+	version		bigint NOT NULL DEFAULT 0
+);
+
+CREATE OR REPLACE FUNCTION bump_table_version() RETURNS trigger AS $$
+BEGIN
+	INSERT INTO table_versions (table_name, version) VALUES (TG_TABLE_NAME, 1)
+		ON CONFLICT (table_name) DO UPDATE SET version = table_versions.version + 1;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS daily_quotes_version ON daily_quotes;
+CREATE TRIGGER daily_quotes_version AFTER INSERT OR UPDATE OR DELETE ON daily_quotes
+	FOR EACH STATEMENT EXECUTE PROCEDURE bump_table_version();
+
+DROP TRIGGER IF EXISTS daily_investors_version ON daily_investors;
+CREATE TRIGGER daily_investors_version AFTER INSERT OR UPDATE OR DELETE ON daily_investors
+	FOR EACH STATEMENT EXECUTE PROCEDURE bump_table_version();
+
+DROP TRIGGER IF EXISTS daily_margin_short_version ON daily_margin_short;
+CREATE TRIGGER daily_margin_short_version AFTER INSERT OR UPDATE OR DELETE ON daily_margin_short
+	FOR EACH STATEMENT EXECUTE PROCEDURE bump_table_version();
+
+DROP TRIGGER IF EXISTS daily_indices_version ON daily_indices;
+CREATE TRIGGER daily_indices_version AFTER INSERT OR UPDATE OR DELETE ON daily_indices
+	FOR EACH STATEMENT EXECUTE PROCEDURE bump_table_version();
+
+DROP TRIGGER IF EXISTS weekly_quotes_version ON weekly_quotes;
+CREATE TRIGGER weekly_quotes_version AFTER INSERT OR UPDATE OR DELETE ON weekly_quotes
+	FOR EACH STATEMENT EXECUTE PROCEDURE bump_table_version();
+
+DROP TRIGGER IF EXISTS monthly_quotes_version ON monthly_quotes;
+CREATE TRIGGER monthly_quotes_version AFTER INSERT OR UPDATE OR DELETE ON monthly_quotes
+	FOR EACH STATEMENT EXECUTE PROCEDURE bump_table_version();
+
+DROP TRIGGER IF EXISTS index_values_version ON index_values;
+CREATE TRIGGER index_values_version AFTER INSERT OR UPDATE OR DELETE ON index_values
+	FOR EACH STATEMENT EXECUTE PROCEDURE bump_table_version();
+
+DROP TRIGGER IF EXISTS index_investors_version ON index_investors;
+CREATE TRIGGER index_investors_version AFTER INSERT OR UPDATE OR DELETE ON index_investors
+	FOR EACH STATEMENT EXECUTE PROCEDURE bump_table_version();
+
+DROP TRIGGER IF EXISTS index_margin_short_version ON index_margin_short;
+CREATE TRIGGER index_margin_short_version AFTER INSERT OR UPDATE OR DELETE ON index_margin_short
+	FOR EACH STATEMENT EXECUTE PROCEDURE bump_table_version();
@@ -0,0 +1,8 @@
+-- a hypertable can not be turned back into a plain table in place, restore
+-- the tables from a pg_dump instead
+
+DO $$
+BEGIN
+	RAISE EXCEPTION 'hypertables can not be converted back, restore from a dump';
+END
+$$;
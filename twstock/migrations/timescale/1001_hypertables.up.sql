@@ -0,0 +1,14 @@
+-- TimescaleDB hypertables, existing rows are moved into chunks.  The unique
+-- constraints already include the time column.
+
+CREATE EXTENSION IF NOT EXISTS timescaledb;
+
+SELECT create_hypertable('daily_quotes', 'trade_date',
+	chunk_time_interval => INTERVAL '3 months', migrate_data => true, if_not_exists => true);
+
+-- about 6 million rows a trading day
+SELECT create_hypertable('trades_5seconds', 'trade_datetime',
+	chunk_time_interval => INTERVAL '1 day', migrate_data => true, if_not_exists => true);
+
+SELECT create_hypertable('indices_5seconds', 'trade_datetime',
+	chunk_time_interval => INTERVAL '1 month', migrate_data => true, if_not_exists => true);
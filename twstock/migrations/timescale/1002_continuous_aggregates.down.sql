@@ -0,0 +1,2 @@
+DROP MATERIALIZED VIEW IF EXISTS quotes_monthly;
+DROP MATERIALIZED VIEW IF EXISTS quotes_weekly;
@@ -0,0 +1,41 @@
+-- weekly and monthly OHLCV per security from daily_quotes, refreshed by a
+-- policy.  The first refresh materializes the whole history, later ones only
+-- the changed buckets.  Needs TimescaleDB 2.8 for monthly buckets.
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS quotes_weekly
+WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+SELECT time_bucket(INTERVAL '1 week', trade_date) AS week_date,	-- Monday
+	security_code,
+	first(open_price, trade_date) FILTER (WHERE open_price IS NOT NULL) AS open_price,
+	max(highest_price) AS highest_price,
+	min(lowest_price) AS lowest_price,
+	last(close_price, trade_date) FILTER (WHERE close_price IS NOT NULL) AS close_price,
+	sum(trade_volume) AS trade_volume,
+	sum(trade_amount) AS trade_amount,
+	sum(trade_count) AS trade_count
+FROM daily_quotes
+GROUP BY week_date, security_code
+WITH NO DATA;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS quotes_monthly
+WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+SELECT time_bucket(INTERVAL '1 month', trade_date) AS month_date,
+	security_code,
+	first(open_price, trade_date) FILTER (WHERE open_price IS NOT NULL) AS open_price,
+	max(highest_price) AS highest_price,
+	min(lowest_price) AS lowest_price,
+	last(close_price, trade_date) FILTER (WHERE close_price IS NOT NULL) AS close_price,
+	sum(trade_volume) AS trade_volume,
+	sum(trade_amount) AS trade_amount,
+	sum(trade_count) AS trade_count
+FROM daily_quotes
+GROUP BY month_date, security_code
+WITH NO DATA;
+
+SELECT add_continuous_aggregate_policy('quotes_weekly',
+	start_offset => NULL, end_offset => NULL,
+	schedule_interval => INTERVAL '1 hour', if_not_exists => true);
+
+SELECT add_continuous_aggregate_policy('quotes_monthly',
+	start_offset => NULL, end_offset => NULL,
+	schedule_interval => INTERVAL '1 hour', if_not_exists => true);
@@ -0,0 +1,11 @@
+SELECT remove_compression_policy('indices_5seconds', if_exists => true);
+SELECT decompress_chunk(c, true) FROM show_chunks('indices_5seconds') c;
+ALTER TABLE indices_5seconds SET (timescaledb.compress = false);
+
+SELECT remove_compression_policy('trades_5seconds', if_exists => true);
+SELECT decompress_chunk(c, true) FROM show_chunks('trades_5seconds') c;
+ALTER TABLE trades_5seconds SET (timescaledb.compress = false);
+
+SELECT remove_compression_policy('daily_quotes', if_exists => true);
+SELECT decompress_chunk(c, true) FROM show_chunks('daily_quotes') c;
+ALTER TABLE daily_quotes SET (timescaledb.compress = false);
@@ -0,0 +1,23 @@
+-- compress old chunks segmented by security, crawls only rewrite recent dates
+-- but TimescaleDB 2.11 is needed to DELETE/INSERT into compressed chunks.
+
+ALTER TABLE daily_quotes SET (
+	timescaledb.compress,
+	timescaledb.compress_segmentby = 'security_code',
+	timescaledb.compress_orderby = 'trade_date'
+);
+SELECT add_compression_policy('daily_quotes', INTERVAL '6 months', if_not_exists => true);
+
+ALTER TABLE trades_5seconds SET (
+	timescaledb.compress,
+	timescaledb.compress_segmentby = 'security_code',
+	timescaledb.compress_orderby = 'trade_datetime'
+);
+SELECT add_compression_policy('trades_5seconds', INTERVAL '7 days', if_not_exists => true);
+
+ALTER TABLE indices_5seconds SET (
+	timescaledb.compress,
+	timescaledb.compress_segmentby = 'security_code',
+	timescaledb.compress_orderby = 'trade_datetime'
+);
+SELECT add_compression_policy('indices_5seconds', INTERVAL '7 days', if_not_exists => true);
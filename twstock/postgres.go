@@ -0,0 +1,54 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/lib/pq"
+)
+
+const (
+	DB_USER     = "stock"
+	DB_PASSWORD = "test"
+	DB_NAME     = "stock"
+	DB_HOST     = "data.example.com"
+)
+
+// every written trade date is notified on this channel as
+// "<table> <YYYY-MM-DD> <rows>", 'twstock grpc' listens to it.
+const dailyLoadChannel = "daily_load"
+
+func postgresDSN(dsn string) string {
+	if len(dsn) == 0 {
+		return fmt.Sprintf("user=%s password=%s dbname=%s host=%s sslmode=disable", DB_USER, DB_PASSWORD, DB_NAME, DB_HOST)
+	}
+	return dsn
+}
+
+func openPostgresDB(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", postgresDSN(dsn))
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// openPostgres opens the shared database, tables are created by
+// 'twstock migrate up'.
+func openPostgres(dsn string) (Store, error) {
+	db, err := openPostgresDB(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlStore{
+		db:          db,
+		placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+		lastDateSql: "SELECT to_char(MAX(trade_date), 'YYYYMMDD')::integer FROM %s",
+		notifySql:   "SELECT pg_notify('" + dailyLoadChannel + "', $1)",
+		refreshSql:  "REFRESH MATERIALIZED VIEW CONCURRENTLY adjusted_quotes",
+	}, nil
+}
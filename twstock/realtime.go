@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	urlMISIndex     = "http://mis.twse.com.tw/stock/index.jsp"
+	urlMISStockInfo = "http://mis.twse.com.tw/stock/api/getStockInfo.jsp?json=1&delay=0&ex_ch="
+	// codes in one getStockInfo request
+	misBatchSize = 50
+)
+
+// misStock is one security of getStockInfo.jsp, see note.txt
+type misStock struct {
+	Code        string `json:"c"`
+	Name        string `json:"n"`
+	Market      string `json:"ex"`
+	Time        string `json:"tlong"` // ms since 1970
+	Price       string `json:"z"`
+	TradeVolume string `json:"tv"`
+	Volume      string `json:"v"`
+	Open        string `json:"o"`
+	High        string `json:"h"`
+	Low         string `json:"l"`
+	Reference   string `json:"y"`
+	BidPrices   string `json:"b"` // 217.50_217.00_216.50_216.00_215.50_
+	BidVolumes  string `json:"g"`
+	AskPrices   string `json:"a"`
+	AskVolumes  string `json:"f"`
+}
+
+// quotePoller polls mis.twse for the codes watched by its subscribers and
+// sends a tick to them when a code changes.  The market of a code is not
+// known, so both tse_ and otc_ are asked for.
+type quotePoller struct {
+	client      *http.Client
+	interval    time.Duration
+	mu          sync.Mutex
+	subscribers map[chan *Tick]map[string]bool
+	last        map[string]string // code: time, volume, bids and asks last sent
+}
+
+func newQuotePoller(interval time.Duration) *quotePoller {
+	// getStockInfo needs the session cookie of index.jsp
+	jar, _ := cookiejar.New(nil)
+	return &quotePoller{
+		client:      &http.Client{Jar: jar, Timeout: 10 * time.Second},
+		interval:    interval,
+		subscribers: make(map[chan *Tick]map[string]bool),
+		last:        make(map[string]string),
+	}
+}
+
+func (p *quotePoller) subscribe(watchlist []string) chan *Tick {
+	ch := make(chan *Tick, 64)
+	watch := make(map[string]bool)
+	for _, code := range watchlist {
+		watch[strings.TrimSpace(code)] = true
+	}
+	p.mu.Lock()
+	p.subscribers[ch] = watch
+	p.mu.Unlock()
+	return ch
+}
+
+func (p *quotePoller) unsubscribe(ch chan *Tick) {
+	p.mu.Lock()
+	delete(p.subscribers, ch)
+	p.mu.Unlock()
+}
+
+func (p *quotePoller) watched() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	seen := make(map[string]bool)
+	var watched []string
+	for _, watch := range p.subscribers {
+		for code := range watch {
+			if !seen[code] {
+				seen[code] = true
+				watched = append(watched, code)
+			}
+		}
+	}
+	return watched
+}
+
+// run polls until the process exits, nothing is fetched without
+// subscribers.
+func (p *quotePoller) run() {
+	for {
+		watched := p.watched()
+		for i := 0; i < len(watched); i += misBatchSize {
+			end := i + misBatchSize
+			if end > len(watched) {
+				end = len(watched)
+			}
+			stocks, err := p.fetch(watched[i:end])
+			if err != nil {
+				log.Println("mis.twse", err)
+				continue
+			}
+			p.publish(stocks)
+		}
+		time.Sleep(p.interval)
+	}
+}
+
+func (p *quotePoller) fetch(watched []string) ([]misStock, error) {
+	u, _ := url.Parse(urlMISIndex)
+	if len(p.client.Jar.Cookies(u)) == 0 {
+		resp, err := p.client.Get(urlMISIndex)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+	}
+
+	var channels []string
+	for _, code := range watched {
+		channels = append(channels, "tse_"+code+".tw", "otc_"+code+".tw")
+	}
+	resp, err := p.client.Get(urlMISStockInfo + url.QueryEscape(strings.Join(channels, "|")) + "&_=" + strconv.FormatInt(time.Now().UnixNano()/1e6, 10))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var info struct {
+		Stocks  []misStock `json:"msgArray"`
+		Message string     `json:"rtmessage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	return info.Stocks, nil
+}
+
+func (p *quotePoller) publish(stocks []misStock) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, s := range stocks {
+		key := s.Time + " " + s.Volume + " " + s.BidPrices + s.BidVolumes + " " + s.AskPrices + s.AskVolumes
+		if p.last[s.Code] == key {
+			continue
+		}
+		p.last[s.Code] = key
+		tick := s.tick()
+		for ch, watch := range p.subscribers {
+			if !watch[s.Code] {
+				continue
+			}
+			select {
+			case ch <- tick:
+			default:
+				// a slow subscriber misses ticks rather than holding the others
+			}
+		}
+	}
+}
+
+func (s *misStock) tick() *Tick {
+	tick := &Tick{
+		SecurityCode: s.Code,
+		Name:         s.Name,
+		Market:       s.Market,
+		Price:        misValue(s.Price),
+		TradeVolume:  misValue(s.TradeVolume),
+		Volume:       misValue(s.Volume),
+		Open:         misValue(s.Open),
+		High:         misValue(s.High),
+		Low:          misValue(s.Low),
+		Reference:    misValue(s.Reference),
+		BidPrices:    misValues(s.BidPrices),
+		BidVolumes:   misValues(s.BidVolumes),
+		AskPrices:    misValues(s.AskPrices),
+		AskVolumes:   misValues(s.AskVolumes),
+	}
+	if ms, err := strconv.ParseInt(s.Time, 10, 64); err == nil {
+		tick.Time = timestamppb.New(time.Unix(ms/1000, ms%1000*1e6))
+	}
+	return tick
+}
+
+// misValue returns "" for the "-" of no trade yet.
+func misValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+func misValues(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == '_' })
+}
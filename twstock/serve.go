@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageSize = 1000
+	maxPageSize     = 10000
+)
+
+func init() {
+	addCommand("serve", "read-only HTTP API over the stored tables", runServe)
+}
+
+// server answers
+//
+//	/quotes/{code}?from=&to=	daily_quotes
+//	/investors/{code}		daily_investors
+//	/margin/{code}			daily_margin_short
+//	/indices/{code}			index_values for TAIEX, else daily_indices
+//	/market/{date}?dataset=		every security of a trade date
+//
+// with format=json|csv, limit and offset.  Dates are YYYYMMDD or YYYY-MM-DD.
+type server struct {
+	reader Reader
+}
+
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flagStore := flags.String("db", "postgres", "store: postgres or sqlite")
+	flagDSN := flags.String("dsn", "", "data source name (default: the shared database, or "+defaultSQLiteFile+" for sqlite)")
+	flagAddr := flags.String("addr", ":8080", "listen address")
+	flags.Parse(args)
+
+	store, err := openStore(*flagStore, *flagDSN)
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	defer store.Close()
+	reader, ok := store.(Reader)
+	if !ok {
+		log.Println(*flagStore, "can not be served")
+		os.Exit(1)
+	}
+
+	log.Println("listen", *flagAddr)
+	log.Fatal(http.ListenAndServe(*flagAddr, newServeMux(reader)))
+}
+
+func newServeMux(reader Reader) *http.ServeMux {
+	s := &server{reader}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/quotes/", s.handleCode("/quotes/", "daily_quotes"))
+	mux.HandleFunc("/investors/", s.handleCode("/investors/", "daily_investors"))
+	mux.HandleFunc("/margin/", s.handleCode("/margin/", "daily_margin_short"))
+	mux.HandleFunc("/indices/", s.handleIndex)
+	mux.HandleFunc("/market/", s.handleMarket)
+	return mux
+}
+
+func (s *server) handleCode(prefix, table string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := strings.TrimPrefix(r.URL.Path, prefix)
+		if len(code) == 0 || strings.Contains(code, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		from, to, err := dateRange(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.serveRows(w, r, table, from, to, []string{code})
+	}
+}
+
+// index_values has TAIEX of TSE/dailyindex.go, daily_indices the others of
+// MI_INDEX.  No crawler writes the OTC index yet.
+func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	code := strings.TrimPrefix(r.URL.Path, "/indices/")
+	if code == "TAIEX" {
+		s.handleCode("/indices/", "index_values")(w, r)
+	} else {
+		s.handleCode("/indices/", "daily_indices")(w, r)
+	}
+}
+
+func (s *server) handleMarket(w http.ResponseWriter, r *http.Request) {
+	date, err := parseDate(strings.TrimPrefix(r.URL.Path, "/market/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	name := r.URL.Query().Get("dataset")
+	if len(name) == 0 {
+		name = "quotes"
+	}
+	for _, d := range datasets {
+		if d.name == name {
+			s.serveRows(w, r, d.table, date, date, nil)
+			return
+		}
+	}
+	http.Error(w, "unknown dataset "+name, http.StatusBadRequest)
+}
+
+func parseDate(value string) (time.Time, error) {
+	if len(value) == 10 {
+		return time.Parse("2006-01-02", value)
+	}
+	return time.Parse("20060102", value)
+}
+
+// dateRange reads from and to, the default is the year until today.
+func dateRange(query url.Values) (time.Time, time.Time, error) {
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if value := query.Get("to"); len(value) > 0 {
+		date, err := parseDate(value)
+		if err != nil {
+			return to, to, fmt.Errorf("bad to date %q", value)
+		}
+		to = date
+	}
+	from := to.AddDate(-1, 0, 0)
+	if value := query.Get("from"); len(value) > 0 {
+		date, err := parseDate(value)
+		if err != nil {
+			return from, to, fmt.Errorf("bad from date %q", value)
+		}
+		from = date
+	}
+	return from, to, nil
+}
+
+func (s *server) serveRows(w http.ResponseWriter, r *http.Request, table string, from, to time.Time, codes []string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	limit := defaultPageSize
+	if value := query.Get("limit"); len(value) > 0 {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > maxPageSize {
+			http.Error(w, fmt.Sprintf("limit must be 1 to %d", maxPageSize), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	offset := 0
+	if value := query.Get("offset"); len(value) > 0 {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, "bad offset", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+	format := query.Get("format")
+	if len(format) == 0 && strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		format = "csv"
+	}
+
+	// the validator is checked before the page is read, a 304 only costs
+	// the version query
+	q := rowQuery{from: from, to: to, codes: codes, limit: limit + 1, offset: offset}
+	if vr, ok := s.reader.(versionReader); ok {
+		version, err := vr.rowsVersion(table, q)
+		if err != nil {
+			log.Println(table, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		h := fnv.New64a()
+		h.Write([]byte(version + " " + r.URL.RequestURI() + " " + format))
+		etag := fmt.Sprintf(`"%x"`, h.Sum64())
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "max-age=60")
+		if match := r.Header.Get("If-None-Match"); len(match) > 0 && strings.Contains(match, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	// one more row than the page tells if there is a next one
+	rows, err := s.reader.ReadRows(table, q)
+	if err != nil {
+		log.Println(table, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	columns, kinds, err := columnTypes(rows)
+	if err != nil {
+		log.Println(table, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	var page [][]sql.NullString
+	more := false
+	for rows.Next() {
+		if len(page) == limit {
+			more = true
+			break
+		}
+		values := make([]sql.NullString, len(columns))
+		scanArgs := make([]interface{}, len(columns))
+		for j := range values {
+			scanArgs[j] = &values[j]
+		}
+		if err := rows.Scan(scanArgs...); err != nil {
+			log.Println(table, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		for j, kind := range kinds {
+			// postgres returns dates as RFC3339
+			if kind == typeDate && len(values[j].String) > 10 {
+				values[j].String = values[j].String[:10]
+			}
+		}
+		page = append(page, values)
+	}
+	if err := rows.Err(); err != nil {
+		log.Println(table, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	next := ""
+	if more {
+		query.Set("offset", strconv.Itoa(offset+limit))
+		next = r.URL.Path + "?" + query.Encode()
+	}
+
+	var body bytes.Buffer
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		err = writeCSVPage(&body, columns, page)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		err = writeJSONPage(&body, columns, kinds, page, next)
+	}
+	if err != nil {
+		log.Println(table, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	if len(next) > 0 {
+		w.Header().Set("Link", "<"+next+`>; rel="next"`)
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(body.Len()))
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(body.Bytes())
+}
+
+// versionReader is implemented by the database stores.
+type versionReader interface {
+	// rowsVersion returns a string which changes when the rows of q do.
+	rowsVersion(table string, q rowQuery) (string, error)
+}
+
+// rowsVersion is the count and last date of the rows and the write counter
+// of the table, which changes when a date is rewritten in place too.
+func (s *sqlStore) rowsVersion(table string, q rowQuery) (string, error) {
+	codeColumn, ok := exportTables[table]
+	if !ok {
+		return "", fmt.Errorf("unknown table %q", table)
+	}
+	where, args := s.where(codeColumn, q)
+	var count int64
+	var last sql.NullString
+	if err := s.db.QueryRow("SELECT COUNT(*), MAX(trade_date) FROM "+table+where, args...).Scan(&count, &last); err != nil {
+		return "", err
+	}
+	var version int64
+	err := s.db.QueryRow("SELECT version FROM table_versions WHERE table_name = "+s.placeholder(1), table).Scan(&version)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	return fmt.Sprintf("%d %s %d", count, last.String, version), nil
+}
+
+func writeCSVPage(b *bytes.Buffer, columns []string, page [][]sql.NullString) error {
+	csvw := csv.NewWriter(b)
+	csvw.Write(columns)
+	record := make([]string, len(columns))
+	for _, values := range page {
+		for i, v := range values {
+			record[i] = v.String
+		}
+		csvw.Write(record)
+	}
+	csvw.Flush()
+	return csvw.Error()
+}
+
+// writeJSONPage writes {"rows":[...],"next":"..."}, each row is an object in
+// the column order with numbers as JSON numbers and nulls.
+func writeJSONPage(b *bytes.Buffer, columns []string, kinds []int, page [][]sql.NullString, next string) error {
+	b.WriteString(`{"rows":[`)
+	for n, values := range page {
+		if n > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n{")
+		for i, v := range values {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			name, _ := json.Marshal(columns[i])
+			b.Write(name)
+			b.WriteString(":")
+			if !v.Valid {
+				b.WriteString("null")
+			} else if _, err := strconv.ParseFloat(v.String, 64); err == nil && kinds[i] == typeNumber {
+				b.WriteString(v.String)
+			} else {
+				value, err := json.Marshal(v.String)
+				if err != nil {
+					return err
+				}
+				b.Write(value)
+			}
+		}
+		b.WriteString("}")
+	}
+	b.WriteString("]")
+	if len(next) > 0 {
+		value, _ := json.Marshal(next)
+		b.WriteString(`,"next":`)
+		b.Write(value)
+	}
+	b.WriteString("}\n")
+	return nil
+}
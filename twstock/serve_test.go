@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *sqlStore) {
+	store, err := openSQLite(filepath.Join(t.TempDir(), "twstock.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	monday := time.Date(2018, 6, 25, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		quote := Quote{SecurityCode: "2330", Volume: "1000", Count: "10", Amount: "225000",
+			Open: "225", High: "226", Low: "224", Close: "225"}
+		if err := store.WriteQuotes(monday.AddDate(0, 0, i), []Quote{quote}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := store.(*sqlStore)
+	if _, err := s.db.Exec("INSERT INTO index_values (trade_date, index_code, close_value) VALUES ('2018-06-29', 'TAIEX', 10836.91)"); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(newServeMux(s))
+	t.Cleanup(ts.Close)
+	return ts, s
+}
+
+func get(t *testing.T, url string, header http.Header) *http.Response {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestServePaging(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	var dates []string
+	next := "/quotes/2330?from=20180625&to=20180629&limit=2"
+	for pages := 0; len(next) > 0; pages++ {
+		if pages == 3 {
+			t.Fatal("more than 3 pages")
+		}
+		resp := get(t, ts.URL+next, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal(resp.Status)
+		}
+		var page struct {
+			Rows []map[string]interface{} `json:"rows"`
+			Next string                   `json:"next"`
+		}
+		err := json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if link := resp.Header.Get("Link"); len(page.Next) > 0 && !strings.Contains(link, page.Next) {
+			t.Errorf("Link %q, next %q", link, page.Next)
+		}
+		for _, row := range page.Rows {
+			dates = append(dates, row["trade_date"].(string))
+		}
+		next = page.Next
+	}
+	if got := strings.Join(dates, " "); got != "2018-06-25 2018-06-26 2018-06-27 2018-06-28 2018-06-29" {
+		t.Errorf("dates %s", got)
+	}
+}
+
+func TestServeCSV(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	resp := get(t, ts.URL+"/quotes/2330?from=20180625&to=20180626&format=csv", nil)
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Content-Type %s", ct)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "trade_date,") || !strings.HasPrefix(lines[1], "2018-06-25,2330,") {
+		t.Errorf("csv %q", lines)
+	}
+}
+
+func TestServeNotModified(t *testing.T) {
+	ts, _ := newTestServer(t)
+	url := ts.URL + "/quotes/2330?from=20180625&to=20180629"
+
+	resp := get(t, url, nil)
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	if len(etag) == 0 {
+		t.Fatal("no ETag")
+	}
+	resp = get(t, url, http.Header{"If-None-Match": {etag}})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("%s, want 304", resp.Status)
+	}
+
+	// another page is another resource
+	resp = get(t, url+"&limit=2", http.Header{"If-None-Match": {etag}})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("%s, want 200", resp.Status)
+	}
+}
+
+// A date rewritten in place keeps the row count and last date, the table
+// index_values has no crawl_status either.
+func TestServeRewrittenDate(t *testing.T) {
+	ts, s := newTestServer(t)
+	url := ts.URL + "/indices/TAIEX?from=20180629&to=20180629"
+
+	resp := get(t, url, nil)
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+
+	_, err := s.db.Exec("DELETE FROM index_values WHERE trade_date = '2018-06-29'; INSERT INTO index_values (trade_date, index_code, close_value) VALUES ('2018-06-29', 'TAIEX', 10900)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = get(t, url, http.Header{"If-None-Match": {etag}})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == etag {
+		t.Errorf("%s with ETag %s after the rewrite", resp.Status, resp.Header.Get("ETag"))
+	}
+}
+
+func TestServeIndexSQLite(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	for _, path := range []string{"/indices/TAIEX?from=20180629&to=20180629", "/indices/Semiconductor"} {
+		resp := get(t, ts.URL+path, nil)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: %s", path, resp.Status)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const defaultSQLiteFile = "twstock.db"
+
+// the daily and index tables of migrations/0001_base.up.sql,
+// shares_outstanding of 0014, the bars of 0015_period_quotes.up.sql, crawl_runs and crawl_status of
+// 0016 and 0017, table_versions of 0019, dates are stored as YYYY-MM-DD text
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS daily_quotes (
+	trade_date		text,
+	security_code	text,
+	close_price		numeric,
+	price_change	numeric,
+	open_price		numeric,
+	highest_price	numeric,
+	lowest_price	numeric,
+	trade_volume	numeric,
+	trade_amount	numeric,
+	trade_count		numeric,
+	last_bid_price	numeric,
+	last_bid_volume	numeric,
+	last_ask_price	numeric,
+	last_ask_volume	numeric,
+	UNIQUE (trade_date, security_code)
+);
+
+CREATE TABLE IF NOT EXISTS daily_investors (
+	trade_date			text,
+	security_code		text,
+	foreign_buy			numeric,
+	foreign_sell		numeric,
+	foreign_diff		numeric,
+	trust_buy			numeric,
+	trust_sell			numeric,
+	trust_diff			numeric,
+	dealer_diff			numeric,
+	dealer_self_buy		numeric,
+	dealer_self_sell	numeric,
+	dealer_self_diff	numeric,
+	dealer_hedge_buy	numeric,
+	dealer_hedge_sell	numeric,
+	dealer_hedge_diff	numeric,
+	investors_diff		numeric,
+	foreign_self_buy	numeric,
+	foreign_self_sell	numeric,
+	foreign_self_diff	numeric,
+	UNIQUE (trade_date, security_code)
+);
+
+CREATE TABLE IF NOT EXISTS daily_margin_short (
+	trade_date			text,
+	security_code		text,
+	margin_new			numeric,
+	margin_redemption	numeric,
+	margin_outstanding	numeric,
+	margin_last_remain	numeric,
+	margin_remain		numeric,
+	margin_limit		numeric,
+	short_redemption	numeric,
+	short_new			numeric,
+	short_outstanding	numeric,
+	short_last_remain	numeric,
+	short_remain		numeric,
+	short_limit			numeric,
+	margin_and_short	numeric,
+	UNIQUE (trade_date, security_code)
+);
+
+CREATE TABLE IF NOT EXISTS daily_indices (
+	trade_date		text,
+	security_code	text,
+	index_value		numeric,
+	trade_volume	numeric,
+	trade_amount	numeric,
+	trade_count		numeric,
+	UNIQUE (trade_date, security_code)
+);
+
+CREATE TABLE IF NOT EXISTS index_values (
+	trade_date		text,
+	index_code		text,
+	open_value		numeric,
+	highest_value	numeric,
+	lowest_value	numeric,
+	close_value		numeric,
+	trade_volume	numeric,
+	trade_amount	numeric,
+	trade_count		numeric,
+	UNIQUE (trade_date, index_code)
+);
+
+CREATE TABLE IF NOT EXISTS index_investors (
+	trade_date			text,
+	index_code			text,
+	dealer_self_buy		numeric,
+	dealer_self_sell	numeric,
+	dealer_self_diff	numeric,
+	dealer_hedge_buy	numeric,
+	dealer_hedge_sell	numeric,
+	dealer_hedge_diff	numeric,
+	trust_buy			numeric,
+	trust_sell			numeric,
+	trust_diff			numeric,
+	foreign_buy			numeric,
+	foreign_sell		numeric,
+	foreign_diff		numeric,
+	total_buy			numeric,
+	total_sell			numeric,
+	total_diff			numeric,
+	foreign_self_buy	numeric,
+	foreign_self_sell	numeric,
+	foreign_self_diff	numeric,
+	UNIQUE (trade_date, index_code)
+);
+
+CREATE TABLE IF NOT EXISTS index_margin_short (
+	trade_date					text,
+	index_code					text,
+	margin_new					numeric,
+	margin_redemption			numeric,
+	margin_outstanding			numeric,
+	margin_last_remain			numeric,
+	margin_remain				numeric,
+	short_redemption			numeric,
+	short_new					numeric,
+	short_outstanding			numeric,
+	short_last_remain			numeric,
+	short_remain				numeric,
+	margin_new_value			numeric,
+	margin_redemption_value		numeric,
+	margin_outstanding_value	numeric,
+	margin_last_remain_value	numeric,
+	margin_remain_value			numeric,
+	UNIQUE (trade_date, index_code)
+);
+
+CREATE TABLE IF NOT EXISTS shares_outstanding (
+	trade_date		text,
+	security_code	text,
+	shares			numeric,
+	UNIQUE (trade_date, security_code)
+);
+
+CREATE TABLE IF NOT EXISTS weekly_quotes (
+	trade_date		text,
+	security_code	text,
+	open_price		numeric,
+	highest_price	numeric,
+	lowest_price	numeric,
+	close_price		numeric,
+	trade_volume	numeric,
+	trade_amount	numeric,
+	trade_count		numeric,
+	trade_days		numeric,
+	UNIQUE (trade_date, security_code)
+);
+
+CREATE TABLE IF NOT EXISTS monthly_quotes (
+	trade_date		text,
+	security_code	text,
+	open_price		numeric,
+	highest_price	numeric,
+	lowest_price	numeric,
+	close_price		numeric,
+	trade_volume	numeric,
+	trade_amount	numeric,
+	trade_count		numeric,
+	trade_days		numeric,
+	UNIQUE (trade_date, security_code)
+);
+
+CREATE TABLE IF NOT EXISTS crawl_runs (
+	id				integer PRIMARY KEY,
+	dataset			text,
+	trade_date		text,
+	started_at		timestamp,
+	finished_at		timestamp,
+	attempts		integer,
+	status			text,
+	row_count		integer,
+	error			text
+);
+
+CREATE TABLE IF NOT EXISTS table_versions (
+	table_name		text PRIMARY KEY,
+	version			integer NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS crawl_status (
+	dataset			text,
+	market			text,
+	trade_date		text,
+	status			text,
+	row_count		integer,
+	fetched_at		timestamp,
+	checksum		text,
+	UNIQUE (dataset, market, trade_date)
+);
+`
+
+// openSQLite opens or creates a local database file, so the crawlers can
+// run without the shared postgres.
+func openSQLite(dsn string) (Store, error) {
+	if len(dsn) == 0 {
+		dsn = defaultSQLiteFile
+	}
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema + sqliteVersionTriggers()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqlStore{
+		db:          db,
+		placeholder: func(n int) string { return "?" },
+		lastDateSql: "SELECT CAST(strftime('%%Y%%m%%d', MAX(trade_date)) AS integer) FROM %s",
+	}, nil
+}
+
+// sqliteVersionTriggers counts the writes of the served tables in
+// table_versions, sqlite has row triggers only and one event per trigger.
+func sqliteVersionTriggers() string {
+	var tables []string
+	for table := range exportTables {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	triggers := ""
+	for _, table := range tables {
+		for _, event := range []string{"INSERT", "UPDATE", "DELETE"} {
+			triggers += fmt.Sprintf(`
+CREATE TRIGGER IF NOT EXISTS %s_%s_version AFTER %s ON %s
+BEGIN
+	INSERT OR IGNORE INTO table_versions (table_name, version) VALUES ('%s', 0);
+	UPDATE table_versions SET version = version + 1 WHERE table_name = '%s';
+END;
+`, table, strings.ToLower(event), event, table, table, table)
+		}
+	}
+	return triggers
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Store is where the crawlers write.  Numbers are kept as the strings
+// parsed from the exchanges, an empty string is stored as null.
+type Store interface {
+	// LastTradeDate returns the latest trade date in table as YYYYMMDD.
+	LastTradeDate(table string) (int, error)
+	// Write* replace every row of the trade date.
+	WriteQuotes(date time.Time, quotes []Quote) error
+	WriteInvestors(date time.Time, investors []Investor) error
+	WriteMarginShort(date time.Time, margins []MarginShort) error
+	Close() error
+}
+
+type Quote struct {
+	SecurityCode string
+	Volume       string
+	Count        string
+	Amount       string
+	Open         string
+	High         string
+	Low          string
+	Close        string
+	BidPrice     string
+	BidVolume    string
+	AskPrice     string
+	AskVolume    string
+}
+
+var quoteColumns = []string{"security_code", "trade_volume", "trade_count", "trade_amount", "open_price", "highest_price", "lowest_price", "close_price", "last_bid_price", "last_bid_volume", "last_ask_price", "last_ask_volume"}
+
+func (q *Quote) values() []string {
+	return []string{q.SecurityCode, q.Volume, q.Count, q.Amount, q.Open, q.High, q.Low, q.Close, q.BidPrice, q.BidVolume, q.AskPrice, q.AskVolume}
+}
+
+type Investor struct {
+	SecurityCode    string
+	ForeignBuy      string
+	ForeignSell     string
+	ForeignDiff     string
+	ForeignSelfBuy  string
+	ForeignSelfSell string
+	ForeignSelfDiff string
+	TrustBuy        string
+	TrustSell       string
+	TrustDiff       string
+	DealerDiff      string
+	DealerSelfBuy   string
+	DealerSelfSell  string
+	DealerSelfDiff  string
+	DealerHedgeBuy  string
+	DealerHedgeSell string
+	DealerHedgeDiff string
+	InvestorsDiff   string
+}
+
+var investorColumns = []string{"security_code", "foreign_buy", "foreign_sell", "foreign_diff", "foreign_self_buy", "foreign_self_sell", "foreign_self_diff", "trust_buy", "trust_sell", "trust_diff", "dealer_diff", "dealer_self_buy", "dealer_self_sell", "dealer_self_diff", "dealer_hedge_buy", "dealer_hedge_sell", "dealer_hedge_diff", "investors_diff"}
+
+func (v *Investor) values() []string {
+	return []string{v.SecurityCode, v.ForeignBuy, v.ForeignSell, v.ForeignDiff, v.ForeignSelfBuy, v.ForeignSelfSell, v.ForeignSelfDiff,
+		v.TrustBuy, v.TrustSell, v.TrustDiff, v.DealerDiff, v.DealerSelfBuy, v.DealerSelfSell, v.DealerSelfDiff,
+		v.DealerHedgeBuy, v.DealerHedgeSell, v.DealerHedgeDiff, v.InvestorsDiff}
+}
+
+type MarginShort struct {
+	SecurityCode      string
+	MarginNew         string // 融資買進
+	MarginRedemption  string // 融資賣出
+	MarginOutstanding string // 現金償還
+	MarginLastRemain  string // 前日餘額
+	MarginRemain      string // 今日餘額
+	MarginLimit       string // 限額
+	ShortRedemption   string // 融券買進
+	ShortNew          string // 融券賣出
+	ShortOutstanding  string
+	ShortLastRemain   string
+	ShortRemain       string
+	ShortLimit        string
+	MarginAndShort    string // 資券互抵
+}
+
+var marginShortColumns = []string{"security_code", "margin_new", "margin_redemption", "margin_outstanding", "margin_last_remain", "margin_remain", "margin_limit", "short_redemption", "short_new", "short_outstanding", "short_last_remain", "short_remain", "short_limit", "margin_and_short"}
+
+func (m *MarginShort) values() []string {
+	return []string{m.SecurityCode, m.MarginNew, m.MarginRedemption, m.MarginOutstanding, m.MarginLastRemain, m.MarginRemain, m.MarginLimit,
+		m.ShortRedemption, m.ShortNew, m.ShortOutstanding, m.ShortLastRemain, m.ShortRemain, m.ShortLimit, m.MarginAndShort}
+}
+
+func quoteRows(quotes []Quote) [][]string {
+	rows := make([][]string, len(quotes))
+	for i := range quotes {
+		rows[i] = quotes[i].values()
+	}
+	return rows
+}
+
+func investorRows(investors []Investor) [][]string {
+	rows := make([][]string, len(investors))
+	for i := range investors {
+		rows[i] = investors[i].values()
+	}
+	return rows
+}
+
+func marginShortRows(margins []MarginShort) [][]string {
+	rows := make([][]string, len(margins))
+	for i := range margins {
+		rows[i] = margins[i].values()
+	}
+	return rows
+}
+
+// openStore opens the postgres, sqlite or file store, an empty dsn means
+// the default of the driver.
+func openStore(driver, dsn string) (Store, error) {
+	switch driver {
+	case "postgres":
+		return openPostgres(dsn)
+	case "sqlite":
+		return openSQLite(dsn)
+	case "jsonl", "csv":
+		return openFileStore(dsn, driver)
+	}
+	return nil, fmt.Errorf("unknown store %q", driver)
+}
+
+// rowStore writes the rows of a trade date by column name, import uses it
+// to load files without knowing the record types.
+type rowStore interface {
+	replaceRows(table string, date time.Time, columns []string, rows [][]string) error
+}
+
+// sqlStore is shared by both databases, they only differ in placeholders
+// and date functions.
+type sqlStore struct {
+	db          *sql.DB
+	placeholder func(n int) string
+	lastDateSql string // %s is the table
+	notifySql   string // run before commit with the table, date and rows as $1
+	refreshSql  string // run after the quotes of a date are written
+}
+
+func (s *sqlStore) LastTradeDate(table string) (int, error) {
+	var date sql.NullInt64
+	err := s.db.QueryRow(fmt.Sprintf(s.lastDateSql, table)).Scan(&date)
+	if err != nil {
+		return 0, err
+	}
+	if !date.Valid {
+		return 0, fmt.Errorf("%s is empty", table)
+	}
+	return int(date.Int64), nil
+}
+
+func (s *sqlStore) WriteQuotes(date time.Time, quotes []Quote) error {
+	return s.replaceRows("daily_quotes", date, quoteColumns, quoteRows(quotes))
+}
+
+func (s *sqlStore) WriteInvestors(date time.Time, investors []Investor) error {
+	return s.replaceRows("daily_investors", date, investorColumns, investorRows(investors))
+}
+
+func (s *sqlStore) WriteMarginShort(date time.Time, margins []MarginShort) error {
+	return s.replaceRows("daily_margin_short", date, marginShortColumns, marginShortRows(margins))
+}
+
+// refreshQuoteViews updates the views over daily_quotes, adjusted_quotes
+// on postgres.
+func (s *sqlStore) refreshQuoteViews() error {
+	if len(s.refreshSql) == 0 {
+		return nil
+	}
+	_, err := s.db.Exec(s.refreshSql)
+	return err
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+// replaceRows deletes the trade date and inserts rows in one transaction.
+func (s *sqlStore) replaceRows(table string, date time.Time, columns []string, rows [][]string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tradeDate := date.Format("2006-01-02")
+	_, err = tx.Exec("DELETE FROM "+table+" WHERE trade_date = "+s.placeholder(1), tradeDate)
+	if err != nil {
+		return err
+	}
+
+	placeholders := make([]string, len(columns)+1)
+	for i := range placeholders {
+		placeholders[i] = s.placeholder(i + 1)
+	}
+	stmt, err := tx.Prepare("INSERT INTO " + table + " (trade_date, " + strings.Join(columns, ", ") + ") VALUES (" + strings.Join(placeholders, ", ") + ")")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	args := make([]interface{}, len(columns)+1)
+	args[0] = tradeDate
+	for _, row := range rows {
+		for i, value := range row {
+			if len(value) == 0 {
+				args[i+1] = nil
+			} else {
+				args[i+1] = value
+			}
+		}
+		if _, err := stmt.Exec(args...); err != nil {
+			return fmt.Errorf("%s %s: %v", table, row[0], err)
+		}
+	}
+	if len(s.notifySql) > 0 {
+		if _, err := tx.Exec(s.notifySql, fmt.Sprintf("%s %s %d", table, tradeDate, len(rows))); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,535 @@
+// The streaming API of 'twstock grpc'.  twstock.pb.go and
+// twstock_grpc.pb.go are generated into package main with
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//		--go-grpc_out=. --go-grpc_opt=paths=source_relative twstock.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.1
+// 	protoc        (unknown)
+// source: twstock.proto
+
+package main
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Watchlist struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SecurityCodes []string `protobuf:"bytes,1,rep,name=security_codes,json=securityCodes,proto3" json:"security_codes,omitempty"`
+}
+
+func (x *Watchlist) Reset() {
+	*x = Watchlist{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_twstock_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Watchlist) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Watchlist) ProtoMessage() {}
+
+func (x *Watchlist) ProtoReflect() protoreflect.Message {
+	mi := &file_twstock_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Watchlist.ProtoReflect.Descriptor instead.
+func (*Watchlist) Descriptor() ([]byte, []int) {
+	return file_twstock_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Watchlist) GetSecurityCodes() []string {
+	if x != nil {
+		return x.SecurityCodes
+	}
+	return nil
+}
+
+// Prices and volumes are the strings of mis.twse, volumes are in lots
+// (1000 shares) and price is empty before the first trade.
+type Tick struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SecurityCode string                 `protobuf:"bytes,1,opt,name=security_code,json=securityCode,proto3" json:"security_code,omitempty"`
+	Name         string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Market       string                 `protobuf:"bytes,3,opt,name=market,proto3" json:"market,omitempty"` // tse / otc
+	Time         *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=time,proto3" json:"time,omitempty"`
+	Price        string                 `protobuf:"bytes,5,opt,name=price,proto3" json:"price,omitempty"`
+	TradeVolume  string                 `protobuf:"bytes,6,opt,name=trade_volume,json=tradeVolume,proto3" json:"trade_volume,omitempty"` // of this trade
+	Volume       string                 `protobuf:"bytes,7,opt,name=volume,proto3" json:"volume,omitempty"`                              // accumulated
+	Open         string                 `protobuf:"bytes,8,opt,name=open,proto3" json:"open,omitempty"`
+	High         string                 `protobuf:"bytes,9,opt,name=high,proto3" json:"high,omitempty"`
+	Low          string                 `protobuf:"bytes,10,opt,name=low,proto3" json:"low,omitempty"`
+	Reference    string                 `protobuf:"bytes,11,opt,name=reference,proto3" json:"reference,omitempty"` // 參考價
+	BidPrices    []string               `protobuf:"bytes,12,rep,name=bid_prices,json=bidPrices,proto3" json:"bid_prices,omitempty"`
+	BidVolumes   []string               `protobuf:"bytes,13,rep,name=bid_volumes,json=bidVolumes,proto3" json:"bid_volumes,omitempty"`
+	AskPrices    []string               `protobuf:"bytes,14,rep,name=ask_prices,json=askPrices,proto3" json:"ask_prices,omitempty"`
+	AskVolumes   []string               `protobuf:"bytes,15,rep,name=ask_volumes,json=askVolumes,proto3" json:"ask_volumes,omitempty"`
+}
+
+func (x *Tick) Reset() {
+	*x = Tick{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_twstock_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Tick) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Tick) ProtoMessage() {}
+
+func (x *Tick) ProtoReflect() protoreflect.Message {
+	mi := &file_twstock_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Tick.ProtoReflect.Descriptor instead.
+func (*Tick) Descriptor() ([]byte, []int) {
+	return file_twstock_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Tick) GetSecurityCode() string {
+	if x != nil {
+		return x.SecurityCode
+	}
+	return ""
+}
+
+func (x *Tick) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Tick) GetMarket() string {
+	if x != nil {
+		return x.Market
+	}
+	return ""
+}
+
+func (x *Tick) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Tick) GetPrice() string {
+	if x != nil {
+		return x.Price
+	}
+	return ""
+}
+
+func (x *Tick) GetTradeVolume() string {
+	if x != nil {
+		return x.TradeVolume
+	}
+	return ""
+}
+
+func (x *Tick) GetVolume() string {
+	if x != nil {
+		return x.Volume
+	}
+	return ""
+}
+
+func (x *Tick) GetOpen() string {
+	if x != nil {
+		return x.Open
+	}
+	return ""
+}
+
+func (x *Tick) GetHigh() string {
+	if x != nil {
+		return x.High
+	}
+	return ""
+}
+
+func (x *Tick) GetLow() string {
+	if x != nil {
+		return x.Low
+	}
+	return ""
+}
+
+func (x *Tick) GetReference() string {
+	if x != nil {
+		return x.Reference
+	}
+	return ""
+}
+
+func (x *Tick) GetBidPrices() []string {
+	if x != nil {
+		return x.BidPrices
+	}
+	return nil
+}
+
+func (x *Tick) GetBidVolumes() []string {
+	if x != nil {
+		return x.BidVolumes
+	}
+	return nil
+}
+
+func (x *Tick) GetAskPrices() []string {
+	if x != nil {
+		return x.AskPrices
+	}
+	return nil
+}
+
+func (x *Tick) GetAskVolumes() []string {
+	if x != nil {
+		return x.AskVolumes
+	}
+	return nil
+}
+
+type DailyLoadRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// quotes, investors or margin, empty for every dataset
+	Datasets []string `protobuf:"bytes,1,rep,name=datasets,proto3" json:"datasets,omitempty"`
+}
+
+func (x *DailyLoadRequest) Reset() {
+	*x = DailyLoadRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_twstock_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DailyLoadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DailyLoadRequest) ProtoMessage() {}
+
+func (x *DailyLoadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_twstock_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DailyLoadRequest.ProtoReflect.Descriptor instead.
+func (*DailyLoadRequest) Descriptor() ([]byte, []int) {
+	return file_twstock_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *DailyLoadRequest) GetDatasets() []string {
+	if x != nil {
+		return x.Datasets
+	}
+	return nil
+}
+
+type DailyLoad struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Dataset   string                 `protobuf:"bytes,1,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	Table     string                 `protobuf:"bytes,2,opt,name=table,proto3" json:"table,omitempty"`
+	TradeDate string                 `protobuf:"bytes,3,opt,name=trade_date,json=tradeDate,proto3" json:"trade_date,omitempty"` // YYYY-MM-DD
+	Rows      int64                  `protobuf:"varint,4,opt,name=rows,proto3" json:"rows,omitempty"`
+	LoadedAt  *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=loaded_at,json=loadedAt,proto3" json:"loaded_at,omitempty"`
+}
+
+func (x *DailyLoad) Reset() {
+	*x = DailyLoad{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_twstock_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DailyLoad) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DailyLoad) ProtoMessage() {}
+
+func (x *DailyLoad) ProtoReflect() protoreflect.Message {
+	mi := &file_twstock_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DailyLoad.ProtoReflect.Descriptor instead.
+func (*DailyLoad) Descriptor() ([]byte, []int) {
+	return file_twstock_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *DailyLoad) GetDataset() string {
+	if x != nil {
+		return x.Dataset
+	}
+	return ""
+}
+
+func (x *DailyLoad) GetTable() string {
+	if x != nil {
+		return x.Table
+	}
+	return ""
+}
+
+func (x *DailyLoad) GetTradeDate() string {
+	if x != nil {
+		return x.TradeDate
+	}
+	return ""
+}
+
+func (x *DailyLoad) GetRows() int64 {
+	if x != nil {
+		return x.Rows
+	}
+	return 0
+}
+
+func (x *DailyLoad) GetLoadedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LoadedAt
+	}
+	return nil
+}
+
+var File_twstock_proto protoreflect.FileDescriptor
+
+var file_twstock_proto_rawDesc = []byte{
+	0x0a, 0x0d, 0x74, 0x77, 0x73, 0x74, 0x6f, 0x63, 0x6b, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x07, 0x74, 0x77, 0x73, 0x74, 0x6f, 0x63, 0x6b, 0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74,
+	0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x32, 0x0a, 0x09, 0x57, 0x61, 0x74,
+	0x63, 0x68, 0x6c, 0x69, 0x73, 0x74, 0x12, 0x25, 0x0a, 0x0e, 0x73, 0x65, 0x63, 0x75, 0x72, 0x69,
+	0x74, 0x79, 0x5f, 0x63, 0x6f, 0x64, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0d,
+	0x73, 0x65, 0x63, 0x75, 0x72, 0x69, 0x74, 0x79, 0x43, 0x6f, 0x64, 0x65, 0x73, 0x22, 0xb0, 0x03,
+	0x0a, 0x04, 0x54, 0x69, 0x63, 0x6b, 0x12, 0x23, 0x0a, 0x0d, 0x73, 0x65, 0x63, 0x75, 0x72, 0x69,
+	0x74, 0x79, 0x5f, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x73,
+	0x65, 0x63, 0x75, 0x72, 0x69, 0x74, 0x79, 0x43, 0x6f, 0x64, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12,
+	0x16, 0x0a, 0x06, 0x6d, 0x61, 0x72, 0x6b, 0x65, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x06, 0x6d, 0x61, 0x72, 0x6b, 0x65, 0x74, 0x12, 0x2e, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d,
+	0x70, 0x52, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x12, 0x21, 0x0a,
+	0x0c, 0x74, 0x72, 0x61, 0x64, 0x65, 0x5f, 0x76, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0b, 0x74, 0x72, 0x61, 0x64, 0x65, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65,
+	0x12, 0x16, 0x0a, 0x06, 0x76, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x76, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6f, 0x70, 0x65, 0x6e,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6f, 0x70, 0x65, 0x6e, 0x12, 0x12, 0x0a, 0x04,
+	0x68, 0x69, 0x67, 0x68, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x69, 0x67, 0x68,
+	0x12, 0x10, 0x0a, 0x03, 0x6c, 0x6f, 0x77, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6c,
+	0x6f, 0x77, 0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x18,
+	0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x72, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65,
+	0x12, 0x1d, 0x0a, 0x0a, 0x62, 0x69, 0x64, 0x5f, 0x70, 0x72, 0x69, 0x63, 0x65, 0x73, 0x18, 0x0c,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x09, 0x62, 0x69, 0x64, 0x50, 0x72, 0x69, 0x63, 0x65, 0x73, 0x12,
+	0x1f, 0x0a, 0x0b, 0x62, 0x69, 0x64, 0x5f, 0x76, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x73, 0x18, 0x0d,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x0a, 0x62, 0x69, 0x64, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x73,
+	0x12, 0x1d, 0x0a, 0x0a, 0x61, 0x73, 0x6b, 0x5f, 0x70, 0x72, 0x69, 0x63, 0x65, 0x73, 0x18, 0x0e,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x09, 0x61, 0x73, 0x6b, 0x50, 0x72, 0x69, 0x63, 0x65, 0x73, 0x12,
+	0x1f, 0x0a, 0x0b, 0x61, 0x73, 0x6b, 0x5f, 0x76, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x73, 0x18, 0x0f,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x0a, 0x61, 0x73, 0x6b, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x73,
+	0x22, 0x2e, 0x0a, 0x10, 0x44, 0x61, 0x69, 0x6c, 0x79, 0x4c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x73,
+	0x22, 0xa7, 0x01, 0x0a, 0x09, 0x44, 0x61, 0x69, 0x6c, 0x79, 0x4c, 0x6f, 0x61, 0x64, 0x12, 0x18,
+	0x0a, 0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x61, 0x62, 0x6c,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x1d,
+	0x0a, 0x0a, 0x74, 0x72, 0x61, 0x64, 0x65, 0x5f, 0x64, 0x61, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x09, 0x74, 0x72, 0x61, 0x64, 0x65, 0x44, 0x61, 0x74, 0x65, 0x12, 0x12, 0x0a,
+	0x04, 0x72, 0x6f, 0x77, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x72, 0x6f, 0x77,
+	0x73, 0x12, 0x37, 0x0a, 0x09, 0x6c, 0x6f, 0x61, 0x64, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70,
+	0x52, 0x08, 0x6c, 0x6f, 0x61, 0x64, 0x65, 0x64, 0x41, 0x74, 0x32, 0x85, 0x01, 0x0a, 0x07, 0x54,
+	0x57, 0x53, 0x74, 0x6f, 0x63, 0x6b, 0x12, 0x33, 0x0a, 0x0c, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x51, 0x75, 0x6f, 0x74, 0x65, 0x73, 0x12, 0x12, 0x2e, 0x74, 0x77, 0x73, 0x74, 0x6f, 0x63, 0x6b,
+	0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x6c, 0x69, 0x73, 0x74, 0x1a, 0x0d, 0x2e, 0x74, 0x77, 0x73,
+	0x74, 0x6f, 0x63, 0x6b, 0x2e, 0x54, 0x69, 0x63, 0x6b, 0x30, 0x01, 0x12, 0x45, 0x0a, 0x12, 0x53,
+	0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x44, 0x61, 0x69, 0x6c, 0x79, 0x4c, 0x6f, 0x61,
+	0x64, 0x12, 0x19, 0x2e, 0x74, 0x77, 0x73, 0x74, 0x6f, 0x63, 0x6b, 0x2e, 0x44, 0x61, 0x69, 0x6c,
+	0x79, 0x4c, 0x6f, 0x61, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x74,
+	0x77, 0x73, 0x74, 0x6f, 0x63, 0x6b, 0x2e, 0x44, 0x61, 0x69, 0x6c, 0x79, 0x4c, 0x6f, 0x61, 0x64,
+	0x30, 0x01, 0x42, 0x09, 0x5a, 0x07, 0x2e, 0x2f, 0x3b, 0x6d, 0x61, 0x69, 0x6e, 0x62, 0x06, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_twstock_proto_rawDescOnce sync.Once
+	file_twstock_proto_rawDescData = file_twstock_proto_rawDesc
+)
+
+func file_twstock_proto_rawDescGZIP() []byte {
+	file_twstock_proto_rawDescOnce.Do(func() {
+		file_twstock_proto_rawDescData = protoimpl.X.CompressGZIP(file_twstock_proto_rawDescData)
+	})
+	return file_twstock_proto_rawDescData
+}
+
+var file_twstock_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_twstock_proto_goTypes = []interface{}{
+	(*Watchlist)(nil),             // 0: twstock.Watchlist
+	(*Tick)(nil),                  // 1: twstock.Tick
+	(*DailyLoadRequest)(nil),      // 2: twstock.DailyLoadRequest
+	(*DailyLoad)(nil),             // 3: twstock.DailyLoad
+	(*timestamppb.Timestamp)(nil), // 4: google.protobuf.Timestamp
+}
+var file_twstock_proto_depIdxs = []int32{
+	4, // 0: twstock.Tick.time:type_name -> google.protobuf.Timestamp
+	4, // 1: twstock.DailyLoad.loaded_at:type_name -> google.protobuf.Timestamp
+	0, // 2: twstock.TWStock.StreamQuotes:input_type -> twstock.Watchlist
+	2, // 3: twstock.TWStock.SubscribeDailyLoad:input_type -> twstock.DailyLoadRequest
+	1, // 4: twstock.TWStock.StreamQuotes:output_type -> twstock.Tick
+	3, // 5: twstock.TWStock.SubscribeDailyLoad:output_type -> twstock.DailyLoad
+	4, // [4:6] is the sub-list for method output_type
+	2, // [2:4] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_twstock_proto_init() }
+func file_twstock_proto_init() {
+	if File_twstock_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_twstock_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Watchlist); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_twstock_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Tick); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_twstock_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DailyLoadRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_twstock_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DailyLoad); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_twstock_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_twstock_proto_goTypes,
+		DependencyIndexes: file_twstock_proto_depIdxs,
+		MessageInfos:      file_twstock_proto_msgTypes,
+	}.Build()
+	File_twstock_proto = out.File
+	file_twstock_proto_rawDesc = nil
+	file_twstock_proto_goTypes = nil
+	file_twstock_proto_depIdxs = nil
+}
@@ -0,0 +1,179 @@
+// The streaming API of 'twstock grpc'.  twstock.pb.go and
+// twstock_grpc.pb.go are generated into package main with
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//		--go-grpc_out=. --go-grpc_opt=paths=source_relative twstock.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: twstock.proto
+
+package main
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	TWStock_StreamQuotes_FullMethodName       = "/twstock.TWStock/StreamQuotes"
+	TWStock_SubscribeDailyLoad_FullMethodName = "/twstock.TWStock/SubscribeDailyLoad"
+)
+
+// TWStockClient is the client API for TWStock service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TWStockClient interface {
+	// StreamQuotes sends a tick whenever a security of the watchlist trades
+	// or its best bids/asks change, polled from mis.twse.
+	StreamQuotes(ctx context.Context, in *Watchlist, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Tick], error)
+	// SubscribeDailyLoad sends an event whenever a crawler has written a
+	// trade date of a dataset.
+	SubscribeDailyLoad(ctx context.Context, in *DailyLoadRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DailyLoad], error)
+}
+
+type tWStockClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTWStockClient(cc grpc.ClientConnInterface) TWStockClient {
+	return &tWStockClient{cc}
+}
+
+func (c *tWStockClient) StreamQuotes(ctx context.Context, in *Watchlist, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Tick], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TWStock_ServiceDesc.Streams[0], TWStock_StreamQuotes_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Watchlist, Tick]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TWStock_StreamQuotesClient = grpc.ServerStreamingClient[Tick]
+
+func (c *tWStockClient) SubscribeDailyLoad(ctx context.Context, in *DailyLoadRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DailyLoad], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TWStock_ServiceDesc.Streams[1], TWStock_SubscribeDailyLoad_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[DailyLoadRequest, DailyLoad]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TWStock_SubscribeDailyLoadClient = grpc.ServerStreamingClient[DailyLoad]
+
+// TWStockServer is the server API for TWStock service.
+// All implementations must embed UnimplementedTWStockServer
+// for forward compatibility.
+type TWStockServer interface {
+	// StreamQuotes sends a tick whenever a security of the watchlist trades
+	// or its best bids/asks change, polled from mis.twse.
+	StreamQuotes(*Watchlist, grpc.ServerStreamingServer[Tick]) error
+	// SubscribeDailyLoad sends an event whenever a crawler has written a
+	// trade date of a dataset.
+	SubscribeDailyLoad(*DailyLoadRequest, grpc.ServerStreamingServer[DailyLoad]) error
+	mustEmbedUnimplementedTWStockServer()
+}
+
+// UnimplementedTWStockServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTWStockServer struct{}
+
+func (UnimplementedTWStockServer) StreamQuotes(*Watchlist, grpc.ServerStreamingServer[Tick]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamQuotes not implemented")
+}
+func (UnimplementedTWStockServer) SubscribeDailyLoad(*DailyLoadRequest, grpc.ServerStreamingServer[DailyLoad]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeDailyLoad not implemented")
+}
+func (UnimplementedTWStockServer) mustEmbedUnimplementedTWStockServer() {}
+func (UnimplementedTWStockServer) testEmbeddedByValue()                 {}
+
+// UnsafeTWStockServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TWStockServer will
+// result in compilation errors.
+type UnsafeTWStockServer interface {
+	mustEmbedUnimplementedTWStockServer()
+}
+
+func RegisterTWStockServer(s grpc.ServiceRegistrar, srv TWStockServer) {
+	// If the following call pancis, it indicates UnimplementedTWStockServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TWStock_ServiceDesc, srv)
+}
+
+func _TWStock_StreamQuotes_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Watchlist)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TWStockServer).StreamQuotes(m, &grpc.GenericServerStream[Watchlist, Tick]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TWStock_StreamQuotesServer = grpc.ServerStreamingServer[Tick]
+
+func _TWStock_SubscribeDailyLoad_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DailyLoadRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TWStockServer).SubscribeDailyLoad(m, &grpc.GenericServerStream[DailyLoadRequest, DailyLoad]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TWStock_SubscribeDailyLoadServer = grpc.ServerStreamingServer[DailyLoad]
+
+// TWStock_ServiceDesc is the grpc.ServiceDesc for TWStock service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TWStock_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "twstock.TWStock",
+	HandlerType: (*TWStockServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamQuotes",
+			Handler:       _TWStock_StreamQuotes_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeDailyLoad",
+			Handler:       _TWStock_SubscribeDailyLoad_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "twstock.proto",
+}